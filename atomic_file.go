@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var errOutputExists = errors.New("output file already exists")
+
+// atomicFile is written to a temporary file in the same directory as its path,
+// which replaces the file at path only once it's committed, so a failure never leaves a partial file at path.
+type atomicFile struct {
+	*os.File
+	path      string
+	committed bool
+}
+
+// createAtomic creates the temporary file of path, it fails if a file exists at path unless force is set
+func createAtomic(path string, force bool) (*atomicFile, error) {
+	if !force {
+		_, err := os.Lstat(path)
+		if err == nil {
+			return nil, fmt.Errorf("%s: %w", path, errOutputExists)
+		}
+
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+
+	// temporary files are only readable by their owner
+	if err := f.Chmod(0644); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return nil, err
+	}
+
+	return &atomicFile{File: f, path: path}, nil
+}
+
+// preserve gives the temporary file the mode and modification time of info
+func (a *atomicFile) preserve(info fs.FileInfo) error {
+	if err := a.Chmod(info.Mode().Perm()); err != nil {
+		return err
+	}
+
+	return os.Chtimes(a.Name(), time.Now(), info.ModTime())
+}
+
+// commit closes the temporary file and renames it to path, it does nothing once committed
+func (a *atomicFile) commit() error {
+	if a.committed {
+		return nil
+	}
+
+	if err := a.Close(); err != nil {
+		return err
+	}
+
+	if err := os.Rename(a.Name(), a.path); err != nil {
+		return err
+	}
+
+	a.committed = true
+
+	return nil
+}
+
+// abort closes and removes the temporary file, unless it was committed
+func (a *atomicFile) abort() {
+	if a.committed {
+		return
+	}
+
+	a.Close()
+	os.Remove(a.Name())
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAtomicFile(t *testing.T) {
+	t.Run("commit", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "out")
+
+		f, err := createAtomic(path, false)
+		assert.NoError(t, err)
+
+		_, err = f.Write([]byte("data"))
+		assert.NoError(t, err)
+
+		// nothing is at path before commit
+		_, err = os.Stat(path)
+		assert.ErrorIs(t, err, os.ErrNotExist)
+
+		assert.NoError(t, f.commit())
+		f.abort()
+
+		got, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("data"), got)
+	})
+
+	t.Run("abort", func(t *testing.T) {
+		dir := t.TempDir()
+		path := filepath.Join(dir, "out")
+
+		f, err := createAtomic(path, false)
+		assert.NoError(t, err)
+
+		_, err = f.Write([]byte("partial data"))
+		assert.NoError(t, err)
+		f.abort()
+
+		entries, err := os.ReadDir(dir)
+		assert.NoError(t, err)
+		assert.Empty(t, entries)
+	})
+
+	t.Run("existing_file", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "out")
+		assert.NoError(t, os.WriteFile(path, []byte("a larger existing file"), 0644))
+
+		_, err := createAtomic(path, false)
+		assert.ErrorIs(t, err, errOutputExists)
+
+		f, err := createAtomic(path, true)
+		assert.NoError(t, err)
+
+		_, err = f.Write([]byte("data"))
+		assert.NoError(t, err)
+		assert.NoError(t, f.commit())
+
+		// the existing file is replaced, not overwritten in place
+		got, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("data"), got)
+	})
+}
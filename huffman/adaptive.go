@@ -0,0 +1,281 @@
+package huffman
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+const (
+	// endSymbol marks the end of adaptive huffman coded data, after the 256 byte values
+	endSymbol = 256
+	// maxAdaptiveNodes is the number of nodes in a tree with a leaf for every symbol, and the NYT leaf
+	maxAdaptiveNodes = 2*(endSymbol+2) - 1
+	// adaptiveRoot is the number of the root, the highest numbered node
+	adaptiveRoot = maxAdaptiveNodes - 1
+
+	noNode = -1
+)
+
+// adaptiveNode is a node of an adaptive huffman tree, its index in the tree's nodes is its order number
+type adaptiveNode struct {
+	weight uint64
+	parent int
+	left   int
+	right  int
+	symbol int
+}
+
+// adaptiveTree is a huffman tree updated as symbols are coded, using the FGK algorithm.
+// nodes are numbered so that weights never decrease with the number, and siblings have consecutive numbers.
+// symbols that weren't seen yet are coded by the path to the NYT (not yet transmitted) leaf followed by their value.
+type adaptiveTree struct {
+	nodes  [maxAdaptiveNodes]adaptiveNode
+	leaves [endSymbol + 1]int
+	nyt    int
+	path   []bool
+}
+
+func newAdaptiveTree() *adaptiveTree {
+	t := &adaptiveTree{nyt: adaptiveRoot}
+	t.nodes[t.nyt] = adaptiveNode{parent: noNode, left: noNode, right: noNode, symbol: noNode}
+
+	for i := range t.leaves {
+		t.leaves[i] = noNode
+	}
+
+	return t
+}
+
+// encode writes the code of symbol without updating the tree,
+// new symbols are written as the NYT leaf's code followed by their 9 bits value.
+func (t *adaptiveTree) encode(bw *bitWriter, symbol int) error {
+	leaf := t.leaves[symbol]
+	if leaf == noNode {
+		leaf = t.nyt
+	}
+
+	t.path = t.path[:0]
+	for n := leaf; t.nodes[n].parent != noNode; n = t.nodes[n].parent {
+		t.path = append(t.path, t.nodes[t.nodes[n].parent].right == n)
+	}
+
+	for i := len(t.path) - 1; i >= 0; i-- {
+		if err := bw.writeBit(t.path[i]); err != nil {
+			return err
+		}
+	}
+
+	if leaf != t.nyt {
+		return nil
+	}
+
+	if err := bw.writeBit(symbol>>8 != 0); err != nil {
+		return err
+	}
+
+	return bw.writeByte(byte(symbol))
+}
+
+// decode reads the code of a symbol without updating the tree
+func (t *adaptiveTree) decode(br *bitReader) (int, error) {
+	n := adaptiveRoot
+	for t.nodes[n].left != noNode {
+		bit, err := br.readBit()
+		if err != nil {
+			return 0, err
+		}
+
+		next := t.nodes[n].left
+		if bit {
+			next = t.nodes[n].right
+		}
+
+		n = next
+	}
+
+	if n != t.nyt {
+		return t.nodes[n].symbol, nil
+	}
+
+	high, err := br.readBit()
+	if err != nil {
+		return 0, err
+	}
+
+	low, err := br.readByte()
+	if err != nil {
+		return 0, err
+	}
+
+	symbol := int(low)
+	if high {
+		symbol += 1 << 8
+	}
+
+	if symbol > endSymbol || (symbol < endSymbol && t.leaves[symbol] != noNode) {
+		return 0, fmt.Errorf("invalid new symbol %d: %w", symbol, ErrInvalidCompressedData)
+	}
+
+	return symbol, nil
+}
+
+// update increments the weight of symbol, swapping nodes to keep the sibling property
+func (t *adaptiveTree) update(symbol int) {
+	q := t.leaves[symbol]
+	if q == noNode {
+		// the NYT leaf becomes the parent of a new NYT leaf and the symbol's leaf
+		old := t.nyt
+		t.nyt, q = old-2, old-1
+
+		t.nodes[t.nyt] = adaptiveNode{parent: old, left: noNode, right: noNode, symbol: noNode}
+		t.nodes[q] = adaptiveNode{weight: 1, parent: old, left: noNode, right: noNode, symbol: symbol}
+		t.nodes[old].left, t.nodes[old].right = t.nyt, q
+		t.leaves[symbol] = q
+
+		q = old
+	}
+
+	for ; q != noNode; q = t.nodes[q].parent {
+		// the leader is the highest numbered node with the same weight
+		leader := q
+		for leader+1 < len(t.nodes) && t.nodes[leader+1].weight == t.nodes[q].weight {
+			leader++
+		}
+
+		if leader != q && leader != t.nodes[q].parent {
+			t.swap(q, leader)
+			q = leader
+		}
+
+		t.nodes[q].weight++
+	}
+}
+
+// swap exchanges the subtrees numbered a and b, nodes keep their numbers and parents
+func (t *adaptiveTree) swap(a, b int) {
+	na, nb := &t.nodes[a], &t.nodes[b]
+	na.weight, nb.weight = nb.weight, na.weight
+	na.left, nb.left = nb.left, na.left
+	na.right, nb.right = nb.right, na.right
+	na.symbol, nb.symbol = nb.symbol, na.symbol
+
+	for _, i := range []int{a, b} {
+		n := t.nodes[i]
+		if n.left != noNode {
+			t.nodes[n.left].parent = i
+			t.nodes[n.right].parent = i
+			continue
+		}
+
+		if n.symbol != noNode {
+			t.leaves[n.symbol] = i
+		}
+	}
+}
+
+// encodeAdaptive compresses data read from r in a single pass with adaptive huffman codes,
+// so no code lengths are written, and the end of data is marked by the end symbol.
+// it returns the number of bytes read from r.
+func encodeAdaptive(r io.Reader, w io.Writer) (uint64, error) {
+	size, err := inputSize(r)
+	sizeKnown := err == nil
+
+	h := header{
+		version:      formatVersion,
+		flags:        flagChecksum | flagAdaptive,
+		originalSize: size,
+	}
+
+	if !sizeKnown {
+		h.flags |= flagUnknownSize
+	}
+
+	if err := writeHeader(w, h); err != nil {
+		return 0, fmt.Errorf("failed to write header: %w", err)
+	}
+
+	crc := crc32.NewIEEE()
+	read := uint64(0)
+	t := newAdaptiveTree()
+	bw := newBitWriter(w)
+
+	data := make([]byte, buffSize)
+	for {
+		n, err := r.Read(data)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return 0, err
+		}
+
+		crc.Write(data[:n])
+		read += uint64(n)
+
+		for _, b := range data[:n] {
+			if err := t.encode(bw, int(b)); err != nil {
+				return 0, fmt.Errorf("failed to write compressed data: %w", err)
+			}
+
+			t.update(int(b))
+		}
+
+		if errors.Is(err, io.EOF) {
+			break
+		}
+	}
+
+	if err := t.encode(bw, endSymbol); err != nil {
+		return 0, fmt.Errorf("failed to write compressed data: %w", err)
+	}
+
+	if err := bw.flush(); err != nil {
+		return 0, fmt.Errorf("failed to write compressed data: %w", err)
+	}
+
+	if sizeKnown && read != size {
+		return 0, errInputChanged
+	}
+
+	if _, err := w.Write(crc.Sum(nil)); err != nil {
+		return 0, fmt.Errorf("failed to write checksum: %w", err)
+	}
+
+	return read, nil
+}
+
+// decodeAdaptive decodes data written by encodeAdaptive, and returns the number of decoded bytes
+func decodeAdaptive(br *bufio.Reader, w io.Writer) (uint64, error) {
+	t := newAdaptiveTree()
+	bits := newBitReader(br)
+	bw := bufio.NewWriterSize(w, buffSize)
+
+	written := uint64(0)
+	for {
+		symbol, err := t.decode(bits)
+		if errors.Is(err, io.EOF) {
+			return 0, fmt.Errorf("compressed data ended before the end symbol: %w", ErrInvalidCompressedData)
+		}
+
+		if err != nil {
+			return 0, fmt.Errorf("failed to decompress binary data: %w", err)
+		}
+
+		if symbol == endSymbol {
+			break
+		}
+
+		if err := bw.WriteByte(byte(symbol)); err != nil {
+			return 0, fmt.Errorf("write failed: %w", err)
+		}
+
+		t.update(symbol)
+		written++
+	}
+
+	if err := bw.Flush(); err != nil {
+		return 0, fmt.Errorf("write failed: %w", err)
+	}
+
+	return written, nil
+}
@@ -0,0 +1,149 @@
+package huffman
+
+import (
+	"bufio"
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// assertSiblingProperty checks that weights never decrease with node numbers,
+// and that every internal node weighs as much as its children
+func assertSiblingProperty(t *testing.T, tree *adaptiveTree) {
+	for i := tree.nyt; i < adaptiveRoot; i++ {
+		assert.LessOrEqual(t, tree.nodes[i].weight, tree.nodes[i+1].weight)
+	}
+
+	for i := tree.nyt; i <= adaptiveRoot; i++ {
+		n := tree.nodes[i]
+		if n.left == noNode {
+			continue
+		}
+
+		assert.Equal(t, n.weight, tree.nodes[n.left].weight+tree.nodes[n.right].weight)
+		assert.Equal(t, i, tree.nodes[n.left].parent)
+		assert.Equal(t, i, tree.nodes[n.right].parent)
+	}
+}
+
+func TestAdaptiveTreeUpdate(t *testing.T) {
+	tree := newAdaptiveTree()
+	for _, b := range []byte("abracadabra, mississippi") {
+		tree.update(int(b))
+		assertSiblingProperty(t, tree)
+	}
+
+	assert.Equal(t, uint64(24), tree.nodes[adaptiveRoot].weight)
+	assert.Equal(t, uint64(5), tree.nodes[tree.leaves['a']].weight)
+	assert.Equal(t, uint64(4), tree.nodes[tree.leaves['i']].weight)
+	assert.Equal(t, noNode, tree.leaves['z'])
+}
+
+func TestAdaptiveTreeEncode(t *testing.T) {
+	got := &bytes.Buffer{}
+	bw := newBitWriter(got)
+
+	tree := newAdaptiveTree()
+	for _, b := range []byte("aab") {
+		assert.NoError(t, tree.encode(bw, int(b)))
+		tree.update(int(b))
+	}
+	assert.NoError(t, bw.flush())
+
+	// 'a' is new: empty NYT code, 0 01100001
+	// 'a' is the right child of the root: 1
+	// 'b' is new: NYT code 0, 0 01100010
+	assert.Equal(t, packBits(t, []bool{
+		false, false, true, true, false, false, false, false, true,
+		true,
+		false, false, false, true, true, false, false, false, true, false,
+	}), got.Bytes())
+}
+
+func TestEncodeDecodeAdaptive(t *testing.T) {
+	all := make([]byte, 256)
+	for i := range all {
+		all[i] = byte(i)
+	}
+
+	tests := map[string][]byte{
+		"empty":           {},
+		"single_byte":     []byte("a"),
+		"same_byte":       bytes.Repeat([]byte("a"), 1000),
+		"all_byte_values": bytes.Repeat(all, 10),
+		"random":          randSeq(100000),
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			compressed := &bytes.Buffer{}
+			_, err := encode(bytes.NewReader(input), compressed, options{adaptive: true})
+			assert.NoError(t, err)
+
+			h, err := readHeader(bytes.NewReader(compressed.Bytes()))
+			assert.NoError(t, err)
+			assert.Equal(t, flagChecksum|flagAdaptive, h.flags)
+			assert.Equal(t, uint64(len(input)), h.originalSize)
+
+			got := bytes.NewBuffer(make([]byte, 0, len(input)))
+			err = decode(compressed, got, options{})
+			assert.NoError(t, err)
+			assert.Equal(t, input, got.Bytes())
+		})
+	}
+}
+
+func TestDecodeAdaptive(t *testing.T) {
+	tests := map[string]struct {
+		input    []bool
+		expected []byte
+		hasError bool
+	}{
+		"end_only": {
+			input:    []bool{true, false, false, false, false, false, false, false, false},
+			expected: []byte{},
+		},
+		"valid_data": {
+			// new 'a', 'a', then NYT code 0 and the end symbol
+			input: []bool{
+				false, false, true, true, false, false, false, false, true,
+				true,
+				false, true, false, false, false, false, false, false, false, false,
+			},
+			expected: []byte("aa"),
+		},
+		"missing_end_symbol": {
+			input:    []bool{false, false, true, true, false, false, false, false, true},
+			hasError: true,
+		},
+		"invalid_symbol": {
+			input:    []bool{true, false, false, false, false, false, false, false, true},
+			hasError: true,
+		},
+		"repeated_new_symbol": {
+			// new 'a', then 'a' as a new symbol again
+			input: []bool{
+				false, false, true, true, false, false, false, false, true,
+				false, false, false, true, true, false, false, false, false, true,
+			},
+			hasError: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := bufio.NewReader(bytes.NewReader(packBits(t, tc.input)))
+			got := bytes.NewBuffer(make([]byte, 0, len(tc.expected)))
+			written, err := decodeAdaptive(r, got)
+			if tc.hasError {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, got.Bytes())
+			assert.Equal(t, uint64(len(tc.expected)), written)
+		})
+	}
+}
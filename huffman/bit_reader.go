@@ -0,0 +1,85 @@
+package huffman
+
+import (
+	"io"
+)
+
+// bitReader reads bits from the underlying reader, most significant bit first.
+// bits are buffered in a 64-bit word aligned to its most significant bit, so several bits can be peeked at once.
+type bitReader struct {
+	r     io.ByteReader
+	buf   uint64
+	nbits uint8
+	// read is the number of bytes read from r
+	read uint64
+}
+
+func newBitReader(r io.ByteReader) *bitReader {
+	return &bitReader{r: r}
+}
+
+func (b *bitReader) readBit() (bool, error) {
+	if b.nbits == 0 {
+		if err := b.readByteIntoBuffer(); err != nil {
+			return false, err
+		}
+	}
+
+	bit := b.buf>>63 == 1
+	b.consume(1)
+
+	return bit, nil
+}
+
+func (b *bitReader) readByte() (byte, error) {
+	var c byte
+	for i := 0; i < 8; i++ {
+		bit, err := b.readBit()
+		if err != nil {
+			return 0, err
+		}
+
+		c <<= 1
+		if bit {
+			c |= 1
+		}
+	}
+
+	return c, nil
+}
+
+// fill buffers bytes until more than 56 bits are buffered, or limit bytes were read from the underlying reader.
+// bytes are only read ahead up to limit, so the bytes following the bits are left to the underlying reader.
+func (b *bitReader) fill(limit uint64) error {
+	for b.nbits <= 56 && b.read < limit {
+		if err := b.readByteIntoBuffer(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// peek returns the next n buffered bits without consuming them, bits that aren't buffered are zeros
+func (b *bitReader) peek(n uint8) uint64 {
+	return b.buf >> (64 - n)
+}
+
+// consume drops the next n buffered bits
+func (b *bitReader) consume(n uint8) {
+	b.buf <<= n
+	b.nbits -= n
+}
+
+func (b *bitReader) readByteIntoBuffer() error {
+	c, err := b.r.ReadByte()
+	if err != nil {
+		return err
+	}
+
+	b.buf |= uint64(c) << (56 - b.nbits)
+	b.nbits += 8
+	b.read++
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package huffman
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBitReader(t *testing.T) {
+	tests := map[string]struct {
+		count    int
+		data     []byte
+		expected []bool
+		hasError bool
+	}{
+		"empty": {
+			count:    0,
+			data:     nil,
+			expected: []bool{},
+		},
+		"less_data": {
+			count:    10,
+			data:     []byte("a"),
+			hasError: true,
+		},
+		"valid_data": {
+			count:    9,
+			data:     []byte{254, 128},
+			expected: []bool{true, true, true, true, true, true, true, false, true},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			br := newBitReader(bytes.NewReader(tc.data))
+
+			got := []bool{}
+			for i := 0; i < tc.count; i++ {
+				bit, err := br.readBit()
+				if tc.hasError && err != nil {
+					assert.ErrorIs(t, err, io.EOF)
+					return
+				}
+
+				assert.NoError(t, err)
+				got = append(got, bit)
+			}
+
+			assert.False(t, tc.hasError)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
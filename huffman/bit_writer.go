@@ -0,0 +1,86 @@
+package huffman
+
+import (
+	"bufio"
+	"io"
+)
+
+// bitWriter packs bits into bytes, most significant bit first, and writes them to the underlying writer.
+// bits are accumulated in a 64-bit word, and only written once they fill whole bytes.
+type bitWriter struct {
+	w *bufio.Writer
+	// buf holds nbits pending bits in its least significant bits, the first bit is the most significant one
+	buf   uint64
+	nbits uint8
+	count uint64
+}
+
+func newBitWriter(w io.Writer) *bitWriter {
+	return &bitWriter{w: bufio.NewWriterSize(w, buffSize)}
+}
+
+func (b *bitWriter) writeBit(bit bool) error {
+	if bit {
+		return b.writeBits(1, 1)
+	}
+
+	return b.writeBits(0, 1)
+}
+
+// writeBits writes the length least significant bits of code, most significant bit first.
+// code must not have bits set above them, and length must be at most 64.
+func (b *bitWriter) writeBits(code uint64, length uint8) error {
+	if length > 32 {
+		if err := b.writeBits(code>>32, length-32); err != nil {
+			return err
+		}
+
+		code, length = code&(1<<32-1), 32
+	}
+
+	// at most 7 bits are pending once whole bytes are written, so the code always fits
+	if b.nbits+length > 64 {
+		if err := b.writeWholeBytes(); err != nil {
+			return err
+		}
+	}
+
+	b.buf = b.buf<<length | code
+	b.nbits += length
+	b.count += uint64(length)
+
+	return nil
+}
+
+func (b *bitWriter) writeByte(c byte) error {
+	return b.writeBits(uint64(c), 8)
+}
+
+// writeWholeBytes writes the pending bits that fill whole bytes
+func (b *bitWriter) writeWholeBytes() error {
+	for b.nbits >= 8 {
+		b.nbits -= 8
+		if err := b.w.WriteByte(byte(b.buf >> b.nbits)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// flush pads the last partial byte with zero bits and writes all buffered data to the underlying writer
+func (b *bitWriter) flush() error {
+	if err := b.writeWholeBytes(); err != nil {
+		return err
+	}
+
+	if b.nbits > 0 {
+		if err := b.w.WriteByte(byte(b.buf << (8 - b.nbits))); err != nil {
+			return err
+		}
+
+		b.buf, b.nbits = 0, 0
+	}
+
+	return b.w.Flush()
+}
@@ -0,0 +1,95 @@
+package huffman
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBitWriter(t *testing.T) {
+	tests := map[string]struct {
+		input    []bool
+		expected []byte
+	}{
+		"empty": {
+			input:    nil,
+			expected: nil,
+		},
+		"partial_byte": {
+			input:    []bool{true, false, true},
+			expected: []byte{160},
+		},
+		"full_byte": {
+			input:    []bool{true, true, true, true, true, true, true, false},
+			expected: []byte{254},
+		},
+		"multiple_bytes": {
+			input:    []bool{true, true, true, true, true, true, true, false, true},
+			expected: []byte{254, 128},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := &bytes.Buffer{}
+			bw := newBitWriter(got)
+
+			for _, bit := range tc.input {
+				assert.NoError(t, bw.writeBit(bit))
+			}
+
+			err := bw.flush()
+			assert.NoError(t, err)
+
+			assert.Equal(t, tc.expected, got.Bytes())
+			assert.Equal(t, uint64(len(tc.input)), bw.count)
+		})
+	}
+}
+
+func TestBitWriterWriteBits(t *testing.T) {
+	type code struct {
+		code   uint64
+		length uint8
+	}
+
+	tests := map[string]struct {
+		input    []code
+		expected []byte
+	}{
+		"short_codes": {
+			input:    []code{{0b1, 1}, {0b0, 1}, {0b101, 3}, {0b11, 2}},
+			expected: []byte{0b1010_1110},
+		},
+		"crossing_bytes": {
+			input:    []code{{0b101, 3}, {0b1111_0000_1, 9}},
+			expected: []byte{0b1011_1110, 0b0001_0000},
+		},
+		"filling_the_accumulator": {
+			input:    []code{{1<<63 | 1, 64}, {0b1, 1}, {0xffff_ffff, 32}, {0, 7}},
+			expected: []byte{0x80, 0, 0, 0, 0, 0, 0, 0x01, 0xff, 0xff, 0xff, 0xff, 0x80},
+		},
+		"long_codes": {
+			input:    []code{{0b1, 1}, {1<<39 | 0b11, 40}, {0x1_0000_0001, 33}, {0, 6}},
+			expected: []byte{0xc0, 0, 0, 0, 0x01, 0xc0, 0, 0, 0, 0x40},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := &bytes.Buffer{}
+			bw := newBitWriter(got)
+
+			count := uint64(0)
+			for _, c := range tc.input {
+				assert.NoError(t, bw.writeBits(c.code, c.length))
+				count += uint64(c.length)
+			}
+
+			assert.NoError(t, bw.flush())
+			assert.Equal(t, tc.expected, got.Bytes())
+			assert.Equal(t, count, bw.count)
+		})
+	}
+}
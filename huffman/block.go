@@ -0,0 +1,250 @@
+package huffman
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+)
+
+var errBlockSizeMismatch = fmt.Errorf("decompressed block size doesn't match its size: %w", ErrInvalidCompressedData)
+
+// encodeBlocks splits data read from r into blocks of at most blockSize bytes, and compresses each block with its own tree.
+// every block is preceded by its size as a uvarint, and a zero size ends the blocks.
+// a block is kept in memory while it's compressed, so r is only read once, and up to threads blocks are compressed concurrently.
+// the original size is only written when r can seek, otherwise it isn't known before all blocks are written.
+// blocks are compressed with method, codes are limited to limit bits, and it returns the number of bytes read from r.
+func encodeBlocks(r io.Reader, w io.Writer, blockSize int, threads int, method Method, limit uint8) (uint64, error) {
+	size, err := inputSize(r)
+	sizeKnown := err == nil
+
+	h := header{
+		version:      formatVersion,
+		flags:        flagChecksum | flagBlocks,
+		originalSize: size,
+		method:       method,
+	}
+
+	if !sizeKnown {
+		h.flags |= flagUnknownSize
+	}
+
+	if err := writeHeader(w, h); err != nil {
+		return 0, fmt.Errorf("failed to write header: %w", err)
+	}
+
+	crc := crc32.NewIEEE()
+	read := uint64(0)
+
+	// blocks are read in order, compressed concurrently, then written in order
+	next := func() (task, error) {
+		block := make([]byte, blockSize)
+		n, err := io.ReadFull(r, block)
+		if errors.Is(err, io.EOF) {
+			return nil, nil
+		}
+
+		if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, err
+		}
+
+		crc.Write(block[:n])
+		read += uint64(n)
+
+		return func() ([]byte, error) {
+			buf := &bytes.Buffer{}
+			err := writeBlock(buf, block[:n], method, limit)
+			return buf.Bytes(), err
+		}, nil
+	}
+
+	consume := func(data []byte) error {
+		_, err := w.Write(data)
+		return err
+	}
+
+	if err := runOrdered(threads, next, consume); err != nil {
+		return 0, err
+	}
+
+	if sizeKnown && read != h.originalSize {
+		return 0, errInputChanged
+	}
+
+	if _, err := w.Write(binary.AppendUvarint(nil, 0)); err != nil {
+		return 0, fmt.Errorf("failed to write end of blocks: %w", err)
+	}
+
+	if _, err := w.Write(crc.Sum(nil)); err != nil {
+		return 0, fmt.Errorf("failed to write checksum: %w", err)
+	}
+
+	return read, nil
+}
+
+// writeBlock writes the block's size, followed by the block compressed with method,
+// huffman coded blocks are compressed with a tree built from their bytes, whose codes are limited to limit bits.
+func writeBlock(w io.Writer, block []byte, method Method, limit uint8) error {
+	if _, err := w.Write(binary.AppendUvarint(nil, uint64(len(block)))); err != nil {
+		return fmt.Errorf("failed to write block size: %w", err)
+	}
+
+	switch method {
+	case LZ77:
+		return compressSequences(w, block, limit)
+	case BWT:
+		return compressBWT(w, block, limit)
+	}
+
+	tree, err := buildHuffmanTree(bytes.NewReader(block))
+	if err != nil {
+		return fmt.Errorf("failed to build huffman tree: %w", err)
+	}
+
+	return compressBlock(tree, limit, bytes.NewReader(block), w)
+}
+
+// decodeBlocks decodes blocks written by encodeBlocks, and returns the number of decoded bytes.
+// up to threads blocks are decoded concurrently, so their payloads are read into memory first.
+// when the header's original size is known, blocks can't add up to more than it, so a corrupted block size fails before its block is written.
+func decodeBlocks(br *bufio.Reader, h header, threads int, w io.Writer) (uint64, error) {
+	written := uint64(0)
+	declared := uint64(0)
+
+	// a run of a single symbol is written in pieces of at most defaultBlockSize bytes,
+	// so a corrupted block size can't allocate more memory than a block
+	var run struct {
+		value byte
+		left  uint64
+	}
+
+	// block headers and payloads are read in order, decoded concurrently, then written in order
+	next := func() (task, error) {
+		if run.left == 0 {
+			size, err := binary.ReadUvarint(br)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read block size: %w", err)
+			}
+
+			if size == 0 {
+				return nil, nil
+			}
+
+			if h.flags&flagUnknownSize == 0 && size > h.originalSize-declared {
+				return nil, fmt.Errorf("block sizes exceed the original size %d: %w", h.originalSize, ErrInvalidCompressedData)
+			}
+
+			declared += size
+
+			// blocks of other methods are decoded in memory, from streams whose sizes are bounded by the block's size
+			if h.method != Huffman && size > maxMethodBlockSize {
+				return nil, fmt.Errorf("block size %d is larger than %d: %w", size, maxMethodBlockSize, ErrInvalidCompressedData)
+			}
+
+			switch h.method {
+			case LZ77:
+				return readSequencesTask(br, h.version, size)
+			case BWT:
+				return readBWTTask(br, h.version, size)
+			}
+
+			root, bitsCount, err := readBlockHeader(br, h.version)
+			if err != nil {
+				return nil, err
+			}
+
+			if root == nil {
+				return readStoredTask(br, size)
+			}
+
+			if !root.IsLeaf {
+				return readBlockTask(br, root, bitsCount, size)
+			}
+
+			run.value, run.left = root.Value, size
+		}
+
+		value, n := run.value, min(run.left, defaultBlockSize)
+		run.left -= n
+
+		return func() ([]byte, error) {
+			return bytes.Repeat([]byte{value}, int(n)), nil
+		}, nil
+	}
+
+	consume := func(data []byte) error {
+		if _, err := w.Write(data); err != nil {
+			return fmt.Errorf("write failed: %w", err)
+		}
+
+		written += uint64(len(data))
+
+		return nil
+	}
+
+	if err := runOrdered(threads, next, consume); err != nil {
+		return 0, err
+	}
+
+	return written, nil
+}
+
+// readStoredTask reads the bytes of a stored block of size bytes into memory, and returns the task returning them
+func readStoredTask(br *bufio.Reader, size uint64) (task, error) {
+	// the buffer grows as data is read, so a corrupted size can't allocate more than what's available
+	stored := &bytes.Buffer{}
+	if _, err := io.CopyN(stored, br, int64(min(size, math.MaxInt64))); err != nil {
+		return nil, fmt.Errorf("stored data size is less than given size: %w", ErrInvalidCompressedData)
+	}
+
+	return func() ([]byte, error) {
+		return stored.Bytes(), nil
+	}, nil
+}
+
+// readStreamTask reads a block of size bytes written by compressBlock into memory, and returns the task decoding it
+func readStreamTask(br *bufio.Reader, version uint8, size uint64) (task, error) {
+	root, bitsCount, err := readBlockHeader(br, version)
+	if err != nil {
+		return nil, err
+	}
+
+	if root == nil {
+		return readStoredTask(br, size)
+	}
+
+	if root.IsLeaf {
+		return func() ([]byte, error) {
+			return bytes.Repeat([]byte{root.Value}, int(size)), nil
+		}, nil
+	}
+
+	return readBlockTask(br, root, bitsCount, size)
+}
+
+// readBlockTask reads the payload of a block of size bytes into memory, and returns the task decoding it
+func readBlockTask(br *bufio.Reader, root *node, bitsCount uint64, size uint64) (task, error) {
+	// the buffer grows as data is read, so a corrupted bits count can't allocate more than what's available
+	payload := &bytes.Buffer{}
+	if _, err := io.CopyN(payload, br, int64((bitsCount+7)/8)); err != nil {
+		return nil, fmt.Errorf("compressed data size is less than given size: %w", ErrInvalidCompressedData)
+	}
+
+	return func() ([]byte, error) {
+		block := &bytes.Buffer{}
+		n, err := root.decompress(newBitReader(payload), bitsCount, block)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress binary data: %w", err)
+		}
+
+		if n != size {
+			return nil, errBlockSizeMismatch
+		}
+
+		return block.Bytes(), nil
+	}, nil
+}
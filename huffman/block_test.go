@@ -0,0 +1,117 @@
+package huffman
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeBlocks(t *testing.T) {
+	input := []byte("aab")
+
+	got := &bytes.Buffer{}
+	_, err := encode(bytes.NewReader(input), got, options{blockSize: 2})
+	assert.NoError(t, err)
+
+	want := append(magic[:], formatVersion, flagChecksum|flagBlocks, 0, 0, 0, 0, 0, 0, 0, 3, byte(Huffman))
+	// "aa", a run of 'a'
+	want = append(want, 2, 0, 1, 'a')
+	// "b", a run of 'b'
+	want = append(want, 1, 0, 1, 'b')
+	// end of blocks
+	want = append(want, 0)
+	want = binary.BigEndian.AppendUint32(want, crc32.ChecksumIEEE(input))
+
+	assert.Equal(t, want, got.Bytes())
+}
+
+func TestEncodeDecodeBlocks(t *testing.T) {
+	for _, size := range []int{0, 1, 1000, 50000} {
+		for _, blockSize := range []int{3, 64, 4096, 1 << 20} {
+			for _, threads := range []int{1, 8} {
+				t.Run(fmt.Sprintf("size_%d_block_%d_threads_%d", size, blockSize, threads), func(t *testing.T) {
+					str := randSeq(size)
+					opts := options{blockSize: blockSize, threads: threads}
+
+					compressed := &bytes.Buffer{}
+					_, err := encode(bytes.NewReader(str), compressed, opts)
+					assert.NoError(t, err)
+
+					got := bytes.NewBuffer(make([]byte, 0, len(str)))
+					err = decode(compressed, got, opts)
+					assert.NoError(t, err)
+					assert.Equal(t, str, got.Bytes())
+				})
+			}
+		}
+	}
+}
+
+func TestDecodeBlocks(t *testing.T) {
+	tests := map[string]struct {
+		input    []byte
+		expected []byte
+		hasError bool
+	}{
+		"no_blocks": {
+			input:    []byte{0},
+			expected: nil,
+		},
+		"valid_blocks": {
+			input:    []byte{2, 0, 2, 'a', 1, 'b', 1, 2, 0, 1, 0, 2, 'a', 1, 'b', 1, 1, 128, 0},
+			expected: []byte("aab"),
+		},
+		"single_symbol_blocks": {
+			input:    []byte{2, 0, 1, 'a', 1, 0, 1, 'b', 0},
+			expected: []byte("aab"),
+		},
+		"single_symbol_larger_than_block": {
+			input:    append(binary.AppendUvarint(nil, 5*defaultBlockSize/2), 0, 1, 'a', 0),
+			expected: bytes.Repeat([]byte("a"), 5*defaultBlockSize/2),
+		},
+		"stored_blocks": {
+			input:    []byte{2, 0xff, 0xff, 'a', 'b', 1, 0, 1, 'c', 0},
+			expected: []byte("abc"),
+		},
+		"truncated_stored_block": {
+			input:    []byte{3, 0xff, 0xff, 'a', 'b'},
+			hasError: true,
+		},
+		"no_symbols_block": {
+			input:    []byte{2, 0, 0, 0},
+			hasError: true,
+		},
+		"block_size_mismatch": {
+			input:    []byte{3, 0, 2, 'a', 1, 'b', 1, 2, 0, 0},
+			hasError: true,
+		},
+		"missing_end_of_blocks": {
+			input:    []byte{2, 0, 2, 'a', 1, 'b', 1, 2, 0},
+			hasError: true,
+		},
+		"truncated_block": {
+			input:    []byte{2, 0, 2, 'a', 1},
+			hasError: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := &bytes.Buffer{}
+			written, err := decodeBlocks(bufio.NewReader(bytes.NewReader(tc.input)), header{version: formatVersion, flags: flagBlocks | flagUnknownSize}, 2, got)
+			if tc.hasError {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, got.Bytes())
+			assert.Equal(t, uint64(len(tc.expected)), written)
+		})
+	}
+}
@@ -0,0 +1,366 @@
+package huffman
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+const (
+	// runA and runB are the digits of zero run lengths written in bijective base 2, worth 1 and 2 times their position's power of 2
+	runA = 0
+	runB = 1
+	// escapeSymbol is followed by 0 or 1 for the move-to-front values 254 and 255, which don't fit in a byte once shifted by 1
+	escapeSymbol = 255
+	// storedBWTRow replaces the end marker row of blocks stored as they are
+	storedBWTRow = 0
+)
+
+// suffixArray returns the start positions of the suffixes of s in ascending order,
+// a suffix that is a prefix of another suffix is smaller.
+// suffixes are sorted by prefix doubling, radix sorting them by the ranks of their first k and next k bytes.
+func suffixArray(s []byte) []int32 {
+	n := len(s)
+	sa := make([]int32, n)
+	rank := make([]int32, n)
+	tmp := make([]int32, n)
+	if n == 0 {
+		return sa
+	}
+
+	for i, b := range s {
+		rank[i] = int32(b)
+	}
+
+	// positions ordered by the rank of the suffix k bytes after them, they start in any order for the first pass on single bytes
+	order := make([]int32, n)
+	for i := range order {
+		order[i] = int32(i)
+	}
+
+	count := make([]int32, max(256, n)+1)
+	for k := 0; ; {
+		// stable counting sort by rank, ties keep their order of the second key
+		clear(count)
+		for _, r := range rank {
+			count[r+1]++
+		}
+
+		for i := 1; i < len(count); i++ {
+			count[i] += count[i-1]
+		}
+
+		for _, i := range order {
+			sa[count[rank[i]]] = i
+			count[rank[i]]++
+		}
+
+		// suffixes get the same rank as the previous one when their first 2k bytes are the same
+		second := func(i int32) int32 {
+			if int(i)+k < n {
+				return rank[int(i)+k]
+			}
+
+			return -1
+		}
+
+		tmp[sa[0]] = 0
+		for j := 1; j < n; j++ {
+			tmp[sa[j]] = tmp[sa[j-1]]
+			if rank[sa[j]] != rank[sa[j-1]] || second(sa[j]) != second(sa[j-1]) {
+				tmp[sa[j]]++
+			}
+		}
+
+		rank, tmp = tmp, rank
+		if int(rank[sa[n-1]]) == n-1 {
+			return sa
+		}
+
+		k = max(1, 2*k)
+
+		// suffixes shorter than k have the smallest second key, the others follow in the order of the suffix k bytes after them
+		order = order[:0]
+		for i := max(0, n-k); i < n; i++ {
+			order = append(order, int32(i))
+		}
+
+		for _, i := range sa {
+			if int(i) >= k {
+				order = append(order, i-int32(k))
+			}
+		}
+	}
+}
+
+// bwt returns the last column of the sorted rotations of block followed by an end marker, the burrows-wheeler transform,
+// without the end marker, and the row of the end marker which the inverse transform needs.
+// the end marker is smaller than every byte, so the rotations are sorted like the suffixes of block.
+func bwt(block []byte) ([]byte, int) {
+	sa := suffixArray(block)
+	if len(block) == 0 {
+		return nil, 0
+	}
+
+	// the first row starts with the end marker, it's preceded by the last byte
+	last := make([]byte, 0, len(block))
+	last = append(last, block[len(block)-1])
+	primary := 0
+	for j, i := range sa {
+		if i == 0 {
+			primary = j + 1
+			continue
+		}
+
+		last = append(last, block[i-1])
+	}
+
+	return last, primary
+}
+
+// inverseBWT rebuilds the block whose transform is last, with the end marker at row primary
+func inverseBWT(last []byte, primary int) ([]byte, error) {
+	n := len(last)
+	if n == 0 {
+		return nil, nil
+	}
+
+	if primary < 1 || primary > n {
+		return nil, fmt.Errorf("end marker row %d is out of range: %w", primary, ErrInvalidCompressedData)
+	}
+
+	// starts holds the first row starting with every byte, after the row starting with the end marker
+	starts := [256]int{}
+	for _, b := range last {
+		starts[b]++
+	}
+
+	sum := 1
+	for b, count := range starts {
+		starts[b] = sum
+		sum += count
+	}
+
+	// lf maps every row to the row starting with its last byte
+	lf := make([]int32, n+1)
+	for row := 0; row <= n; row++ {
+		if row == primary {
+			continue
+		}
+
+		b := last[row-boolToInt(row > primary)]
+		lf[row] = int32(starts[b])
+		starts[b]++
+	}
+
+	// the first row ends with the last byte, and every byte is preceded by the last byte of its row
+	block := make([]byte, n)
+	row := 0
+	for i := n - 1; i >= 0; i-- {
+		if row == primary {
+			return nil, fmt.Errorf("end marker is reached before the start of the block: %w", ErrInvalidCompressedData)
+		}
+
+		block[i] = last[row-boolToInt(row > primary)]
+		row = int(lf[row])
+	}
+
+	return block, nil
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+
+	return 0
+}
+
+// moveToFront replaces every byte with its index in a list of bytes, and moves it to the front of the list.
+// repeated bytes, like in the output of bwt, become runs of zeros.
+func moveToFront(data []byte) []byte {
+	list := [256]byte{}
+	for i := range list {
+		list[i] = byte(i)
+	}
+
+	out := make([]byte, len(data))
+	for i, b := range data {
+		j := 0
+		for list[j] != b {
+			j++
+		}
+
+		copy(list[1:j+1], list[:j])
+		list[0] = b
+		out[i] = byte(j)
+	}
+
+	return out
+}
+
+// inverseMoveToFront replaces every index with the byte at that index in the list, and moves the byte to the front of the list
+func inverseMoveToFront(indexes []byte) []byte {
+	list := [256]byte{}
+	for i := range list {
+		list[i] = byte(i)
+	}
+
+	out := make([]byte, len(indexes))
+	for i, j := range indexes {
+		b := list[j]
+		copy(list[1:int(j)+1], list[:j])
+		list[0] = b
+		out[i] = b
+	}
+
+	return out
+}
+
+// encodeZeroRuns writes the lengths of runs of zeros as bijective base 2 numbers of runA and runB digits, least significant first, like bzip2.
+// other values are shifted by 1 past the digits, and the values 254 and 255 are written as escapeSymbol followed by 0 or 1.
+func encodeZeroRuns(data []byte) []byte {
+	out := make([]byte, 0, len(data))
+	run := 0
+	for i := 0; i <= len(data); i++ {
+		if i < len(data) && data[i] == 0 {
+			run++
+			continue
+		}
+
+		for ; run > 0; run = (run - 1) / 2 {
+			out = append(out, byte(runA+(run-1)%2))
+		}
+
+		if i == len(data) {
+			break
+		}
+
+		if v := data[i]; v < escapeSymbol-1 {
+			out = append(out, v+1)
+		} else {
+			out = append(out, escapeSymbol, v-(escapeSymbol-1))
+		}
+	}
+
+	return out
+}
+
+// decodeZeroRuns reverses encodeZeroRuns, it fails if the decoded data is longer than size bytes
+func decodeZeroRuns(data []byte, size uint64) ([]byte, error) {
+	out := make([]byte, 0, min(size, uint64(len(data))))
+	run, weight := uint64(0), uint64(1)
+	for i := 0; i <= len(data); i++ {
+		if i < len(data) && data[i] <= runB {
+			run += weight * uint64(data[i]-runA+1)
+			weight *= 2
+			if run > size-uint64(len(out)) {
+				return nil, fmt.Errorf("run of zeros is longer than the block: %w", ErrInvalidCompressedData)
+			}
+
+			continue
+		}
+
+		out = append(out, make([]byte, run)...)
+		run, weight = 0, 1
+
+		if i == len(data) {
+			break
+		}
+
+		if uint64(len(out)) == size {
+			return nil, fmt.Errorf("move-to-front values are longer than the block: %w", ErrInvalidCompressedData)
+		}
+
+		if data[i] != escapeSymbol {
+			out = append(out, data[i]-1)
+			continue
+		}
+
+		i++
+		if i == len(data) || data[i] > 1 {
+			return nil, fmt.Errorf("invalid escaped move-to-front value: %w", ErrInvalidCompressedData)
+		}
+
+		out = append(out, escapeSymbol-1+data[i])
+	}
+
+	return out, nil
+}
+
+// compressBWT writes the row of the end marker of the block's transform as a uvarint,
+// followed by the zero runs of the move-to-front indexes of the transform, written by writeBlock with its own tree.
+// the end marker is never in the first row, so a block whose transform wouldn't be smaller than its bytes, like random data,
+// is stored as it is after a zero row.
+func compressBWT(w io.Writer, block []byte, limit uint8) error {
+	last, primary := bwt(block)
+
+	buf := &bytes.Buffer{}
+	buf.Write(binary.AppendUvarint(nil, uint64(primary)))
+	if err := writeBlock(buf, encodeZeroRuns(moveToFront(last)), Huffman, limit); err != nil {
+		return fmt.Errorf("failed to write move-to-front indexes: %w", err)
+	}
+
+	if buf.Len() > len(block) {
+		buf.Reset()
+		buf.WriteByte(storedBWTRow)
+		buf.Write(block)
+	}
+
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write block: %w", err)
+	}
+
+	return nil
+}
+
+// readBWTTask reads a block of size bytes written by compressBWT, and returns the task decoding it
+func readBWTTask(br *bufio.Reader, version uint8, size uint64) (task, error) {
+	primary, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read end marker row: %w", err)
+	}
+
+	if primary == storedBWTRow {
+		return readStoredTask(br, size)
+	}
+
+	if primary > size {
+		return nil, fmt.Errorf("end marker row %d is out of range: %w", primary, ErrInvalidCompressedData)
+	}
+
+	runsSize, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read move-to-front indexes size: %w", err)
+	}
+
+	// every index is written in at most 2 bytes
+	if runsSize/2 > size {
+		return nil, fmt.Errorf("move-to-front indexes size %d is too large: %w", runsSize, ErrInvalidCompressedData)
+	}
+
+	runs, err := readStreamTask(br, version, runsSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return func() ([]byte, error) {
+		data, err := runs()
+		if err != nil {
+			return nil, err
+		}
+
+		indexes, err := decodeZeroRuns(data, size)
+		if err != nil {
+			return nil, err
+		}
+
+		if uint64(len(indexes)) != size {
+			return nil, errBlockSizeMismatch
+		}
+
+		return inverseBWT(inverseMoveToFront(indexes), int(primary))
+	}, nil
+}
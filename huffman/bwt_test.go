@@ -0,0 +1,231 @@
+package huffman
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSuffixArray(t *testing.T) {
+	assert.Equal(t, []int32{5, 3, 1, 0, 4, 2}, suffixArray([]byte("banana")))
+
+	rng := rand.New(rand.NewSource(1))
+	for _, n := range []int{0, 1, 2, 10, 100, 1000} {
+		for _, alphabet := range []int{1, 2, 256} {
+			s := make([]byte, n)
+			for i := range s {
+				s[i] = byte(rng.Intn(alphabet))
+			}
+
+			want := make([]int32, n)
+			for i := range want {
+				want[i] = int32(i)
+			}
+
+			sort.Slice(want, func(i, j int) bool {
+				return bytes.Compare(s[want[i]:], s[want[j]:]) < 0
+			})
+
+			assert.Equal(t, want, suffixArray(s), "n=%d alphabet=%d", n, alphabet)
+		}
+	}
+}
+
+func TestBWT(t *testing.T) {
+	// the sorted suffixes are "", a, ana, anana, banana, na and nana, banana is preceded by the end marker
+	last, primary := bwt([]byte("banana"))
+	assert.Equal(t, []byte("annbaa"), last)
+	assert.Equal(t, 4, primary)
+
+	for _, input := range [][]byte{[]byte("a"), []byte("banana"), make([]byte, 1000), randSeq(10000), jsonLines(100)} {
+		last, primary := bwt(input)
+		got, err := inverseBWT(last, primary)
+		assert.NoError(t, err)
+		assert.Equal(t, input, got)
+	}
+}
+
+func TestInverseBWTInvalidRow(t *testing.T) {
+	for _, primary := range []int{0, 7} {
+		_, err := inverseBWT([]byte("annbaa"), primary)
+		assert.ErrorIs(t, err, ErrInvalidCompressedData)
+	}
+
+	// the end marker's row is reached before the first byte
+	_, err := inverseBWT([]byte("annbaa"), 1)
+	assert.ErrorIs(t, err, ErrInvalidCompressedData)
+}
+
+func TestMoveToFront(t *testing.T) {
+	got := moveToFront([]byte("bbbaab"))
+	assert.Equal(t, []byte{'b', 0, 0, 'b', 0, 1}, got)
+	assert.Equal(t, []byte("bbbaab"), inverseMoveToFront(got))
+}
+
+func TestEncodeZeroRuns(t *testing.T) {
+	tests := map[string]struct {
+		input    []byte
+		expected []byte
+	}{
+		"empty": {
+			input:    []byte{},
+			expected: []byte{},
+		},
+		"runs": {
+			// runs of 1, 2, 3 and 4 zeros
+			input:    []byte{0, 1, 0, 0, 1, 0, 0, 0, 1, 0, 0, 0, 0},
+			expected: []byte{runA, 2, runB, 2, runA, runA, 2, runB, runA},
+		},
+		"escaped_values": {
+			input:    []byte{253, 254, 255},
+			expected: []byte{254, escapeSymbol, 0, escapeSymbol, 1},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := encodeZeroRuns(tc.input)
+			assert.Equal(t, tc.expected, got)
+
+			decoded, err := decodeZeroRuns(got, uint64(len(tc.input)))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.input, decoded)
+		})
+	}
+}
+
+func TestDecodeZeroRuns(t *testing.T) {
+	tests := map[string]struct {
+		input []byte
+		size  uint64
+	}{
+		"run_longer_than_size": {
+			input: []byte{runB, runB},
+			size:  5,
+		},
+		"values_longer_than_size": {
+			input: []byte{2, 2},
+			size:  1,
+		},
+		"missing_escaped_value": {
+			input: []byte{escapeSymbol},
+			size:  1,
+		},
+		"invalid_escaped_value": {
+			input: []byte{escapeSymbol, 2},
+			size:  1,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := decodeZeroRuns(tc.input, tc.size)
+			assert.ErrorIs(t, err, ErrInvalidCompressedData)
+		})
+	}
+}
+
+func TestEncodeDecodeBWT(t *testing.T) {
+	tests := map[string]struct {
+		input []byte
+		opts  options
+	}{
+		"empty": {
+			input: nil,
+		},
+		"short": {
+			input: []byte("a"),
+		},
+		"random": {
+			input: randSeq(100000),
+		},
+		"all_bytes": {
+			input: bytes.Repeat([]byte{255, 254, 0, 1, 253}, 1000),
+		},
+		"zeros": {
+			input: make([]byte, 100000),
+		},
+		"json_blocks": {
+			input: jsonLines(10000),
+			opts:  options{blockSize: 100000, threads: 4},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			tc.opts.method = BWT
+
+			compressed := &bytes.Buffer{}
+			_, err := encode(bytes.NewReader(tc.input), compressed, tc.opts)
+			assert.NoError(t, err)
+
+			got := &bytes.Buffer{}
+			err = decode(compressed, got, options{})
+			assert.NoError(t, err)
+			assert.True(t, bytes.Equal(tc.input, got.Bytes()))
+		})
+	}
+}
+
+func TestEncodeDecodeBWTIncompressible(t *testing.T) {
+	input := make([]byte, 1<<20)
+	rand.New(rand.NewSource(1)).Read(input)
+
+	compressed := &bytes.Buffer{}
+	_, err := encode(bytes.NewReader(input), compressed, options{method: BWT})
+	assert.NoError(t, err)
+
+	// a block of a 3 bytes size, stored after a zero row, and the end of blocks
+	assert.Equal(t, len(input)+headerSize+3+1+1+checksumSize, compressed.Len())
+
+	got := &bytes.Buffer{}
+	err = decode(compressed, got, options{})
+	assert.NoError(t, err)
+	assert.True(t, bytes.Equal(input, got.Bytes()))
+}
+
+func TestDecodeBWTSizes(t *testing.T) {
+	header := append(magic[:], formatVersion, flagBlocks, 0, 0, 0, 0, 0, 0, 0, 0, byte(BWT))
+
+	// a block of 2^36 bytes, whose move-to-front indexes are a run of 2^35-2 zeros written in 34 digits
+	input := append(binary.AppendUvarint(bytes.Clone(header), 1<<36), 1, 34, 0, 1, runB)
+	err := decode(bytes.NewReader(input), io.Discard, options{})
+	assert.ErrorIs(t, err, ErrInvalidCompressedData)
+
+	// the same run in a block of 1MiB
+	input = append(binary.AppendUvarint(bytes.Clone(header), 1<<20), 1, 34, 0, 1, runB)
+	err = decode(bytes.NewReader(input), io.Discard, options{})
+	assert.ErrorIs(t, err, ErrInvalidCompressedData)
+}
+
+func TestBWTCompressesText(t *testing.T) {
+	input := jsonLines(10000)
+
+	sizes := map[Method]int{}
+	for _, method := range []Method{Huffman, LZ77, BWT} {
+		compressed := &bytes.Buffer{}
+		_, err := encode(bytes.NewReader(input), compressed, options{method: method})
+		assert.NoError(t, err)
+
+		sizes[method] = compressed.Len()
+	}
+
+	assert.Less(t, sizes[BWT]*3, sizes[Huffman], sizes)
+	assert.Less(t, sizes[BWT], sizes[LZ77], sizes)
+}
+
+func BenchmarkSuffixArray(b *testing.B) {
+	input := jsonLines(10000)
+
+	b.SetBytes(int64(len(input)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		suffixArray(input)
+	}
+}
@@ -0,0 +1,110 @@
+package huffman
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// magic identifies piedpiper compressed data
+var magic = [4]byte{'P', 'I', 'E', 'D'}
+
+const (
+	// shortCountVersion stores the bits count as a big endian uint32, limiting the compressed data to 2^32 bits.
+	// it follows the version bytes of the formats written before the container was introduced.
+	shortCountVersion = 3
+	// varintCountVersion stores the bits count as a uvarint.
+	varintCountVersion = 4
+	// runVersion stores blocks without symbols or of a single symbol without code lengths nor bits.
+	runVersion = 5
+	// storedVersion stores blocks that coding wouldn't make smaller as they are, see compressBlock.
+	storedVersion = 6
+	// formatVersion is the version of the container written by encode, its header ends with the compression method.
+	formatVersion = 7
+)
+
+const (
+	// flagChecksum is set when a crc32 checksum of the original data follows the compressed data
+	flagChecksum uint8 = 1 << iota
+	// flagBlocks is set when the compressed data is split into blocks, each with its own code lengths
+	flagBlocks
+	// flagUnknownSize is set when the original size wasn't known when the header was written, like for piped inputs.
+	// the original size is zero, and the decompressed size is only checked against the blocks' sizes.
+	flagUnknownSize
+	// flagAdaptive is set when the data is coded with adaptive huffman codes, which need no code lengths
+	flagAdaptive
+
+	knownFlags = flagChecksum | flagBlocks | flagUnknownSize | flagAdaptive
+)
+
+// checksumSize is the size of the big endian crc32 checksum (IEEE) that ends the container
+const checksumSize = 4
+
+// headerSize is the size of the magic number, version, flags, original size and compression method.
+// the headers of versions before formatVersion end before the method, their data is coded with Huffman.
+const headerSize = len(magic) + 1 + 1 + 8 + 1
+
+// header is the start of every container, it is followed by the compressed data
+type header struct {
+	version      uint8
+	flags        uint8
+	originalSize uint64
+	method       Method
+}
+
+func writeHeader(w io.Writer, h header) error {
+	buf := make([]byte, headerSize)
+	copy(buf, magic[:])
+	buf[4] = h.version
+	buf[5] = h.flags
+	binary.BigEndian.PutUint64(buf[6:], h.originalSize)
+	buf[14] = byte(h.method)
+
+	_, err := w.Write(buf)
+	return err
+}
+
+func readHeader(r io.Reader) (header, error) {
+	buf := make([]byte, headerSize)
+	if _, err := io.ReadFull(r, buf[:headerSize-1]); err != nil {
+		return header{}, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	if [4]byte(buf[:4]) != magic {
+		return header{}, fmt.Errorf("not a piedpiper archive: %w", ErrInvalidCompressedData)
+	}
+
+	h := header{
+		version:      buf[4],
+		flags:        buf[5],
+		originalSize: binary.BigEndian.Uint64(buf[6:]),
+	}
+
+	if h.version < shortCountVersion || h.version > formatVersion {
+		return header{}, fmt.Errorf("unsupported format version %d: %w", h.version, ErrInvalidCompressedData)
+	}
+
+	if h.flags&^knownFlags != 0 {
+		return header{}, fmt.Errorf("unsupported flags %08b: %w", h.flags, ErrInvalidCompressedData)
+	}
+
+	if h.version < formatVersion {
+		return h, nil
+	}
+
+	if _, err := io.ReadFull(r, buf[headerSize-1:]); err != nil {
+		return header{}, fmt.Errorf("failed to read header: %w", err)
+	}
+
+	h.method = Method(buf[14])
+	if h.method > maxMethod {
+		return header{}, fmt.Errorf("unsupported compression method %d: %w", h.method, ErrInvalidCompressedData)
+	}
+
+	// only huffman coding has a single tree or adaptive codes
+	if h.method != Huffman && (h.flags&flagBlocks == 0 || h.flags&flagAdaptive != 0) {
+		return header{}, fmt.Errorf("compression method %d needs blocks: %w", h.method, ErrInvalidCompressedData)
+	}
+
+	return h, nil
+}
@@ -0,0 +1,77 @@
+package huffman
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteHeader(t *testing.T) {
+	got := &bytes.Buffer{}
+	err := writeHeader(got, header{version: formatVersion, flags: flagBlocks, originalSize: 258, method: LZ77})
+	assert.NoError(t, err)
+	assert.Equal(t, []byte{'P', 'I', 'E', 'D', formatVersion, flagBlocks, 0, 0, 0, 0, 0, 0, 1, 2, byte(LZ77)}, got.Bytes())
+}
+
+func TestReadHeader(t *testing.T) {
+	tests := map[string]struct {
+		input    []byte
+		expected header
+		hasError bool
+	}{
+		"empty": {
+			input:    nil,
+			hasError: true,
+		},
+		"short_header": {
+			input:    []byte{'P', 'I', 'E', 'D', formatVersion, 0},
+			hasError: true,
+		},
+		"invalid_magic": {
+			input:    []byte{'P', 'I', 'P', 'E', formatVersion, 0, 0, 0, 0, 0, 0, 0, 0, 1},
+			hasError: true,
+		},
+		"unsupported_version": {
+			input:    []byte{'P', 'I', 'E', 'D', formatVersion + 1, 0, 0, 0, 0, 0, 0, 0, 0, 1},
+			hasError: true,
+		},
+		"unsupported_flags": {
+			input:    []byte{'P', 'I', 'E', 'D', formatVersion, 128, 0, 0, 0, 0, 0, 0, 0, 1},
+			hasError: true,
+		},
+		"missing_method": {
+			input:    []byte{'P', 'I', 'E', 'D', formatVersion, 0, 0, 0, 0, 0, 0, 0, 1, 2},
+			hasError: true,
+		},
+		"unsupported_method": {
+			input:    []byte{'P', 'I', 'E', 'D', formatVersion, flagBlocks, 0, 0, 0, 0, 0, 0, 1, 2, byte(maxMethod) + 1},
+			hasError: true,
+		},
+		"method_without_blocks": {
+			input:    []byte{'P', 'I', 'E', 'D', formatVersion, 0, 0, 0, 0, 0, 0, 0, 1, 2, byte(LZ77)},
+			hasError: true,
+		},
+		"valid_header": {
+			input:    []byte{'P', 'I', 'E', 'D', formatVersion, flagBlocks, 0, 0, 0, 0, 0, 0, 1, 2, byte(LZ77)},
+			expected: header{version: formatVersion, flags: flagBlocks, originalSize: 258, method: LZ77},
+		},
+		"header_without_method": {
+			input:    []byte{'P', 'I', 'E', 'D', storedVersion, 0, 0, 0, 0, 0, 0, 0, 1, 2},
+			expected: header{version: storedVersion, originalSize: 258, method: Huffman},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := readHeader(bytes.NewReader(tc.input))
+			if tc.hasError {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
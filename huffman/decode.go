@@ -0,0 +1,335 @@
+package huffman
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+)
+
+var errSizeMismatch = fmt.Errorf("decompressed data size doesn't match original size: %w", ErrInvalidCompressedData)
+
+// ErrChecksum is returned when the checksum of the decompressed data doesn't match the one in the container,
+// it wraps ErrInvalidCompressedData.
+var ErrChecksum = fmt.Errorf("checksum mismatch: %w", ErrInvalidCompressedData)
+
+// decode decompresses data read from r and writes it to w
+func decode(r io.Reader, w io.Writer, opts options) error {
+	z, err := newReader(r, opts)
+	if err != nil {
+		return err
+	}
+
+	_, err = z.decode(w)
+	return err
+}
+
+// decode decompresses the data following the header and writes it to w, and returns the number of decoded bytes.
+// like gzip members, containers written one after another, like by piedpiper -c a b, are decoded one after another until EOF.
+func (z *Reader) decode(w io.Writer) (uint64, error) {
+	if z.legacy {
+		return decodeLegacy(z.br, w)
+	}
+
+	total := uint64(0)
+	for {
+		written, err := z.decodeContainer(w)
+		if err != nil {
+			return 0, err
+		}
+
+		total += written
+
+		if _, err := z.br.Peek(1); err != nil {
+			if errors.Is(err, io.EOF) {
+				return total, nil
+			}
+
+			return 0, fmt.Errorf("failed to read compressed data: %w", err)
+		}
+
+		// only another container can follow a container
+		prefix, err := z.br.Peek(len(magic))
+		if err != nil || !bytes.Equal(prefix, magic[:]) {
+			return 0, fmt.Errorf("compressed data size exceeded given size: %w", ErrInvalidCompressedData)
+		}
+
+		z.h, err = readHeader(z.br)
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+// decodeContainer decompresses the data following the header up to the end of its container,
+// and returns the number of decoded bytes
+func (z *Reader) decodeContainer(w io.Writer) (uint64, error) {
+	crc := crc32.NewIEEE()
+	out := io.MultiWriter(w, crc)
+
+	var written uint64
+	var err error
+	switch {
+	case z.h.flags&flagAdaptive != 0:
+		written, err = decodeAdaptive(z.br, out)
+	case z.h.flags&flagBlocks != 0:
+		written, err = decodeBlocks(z.br, z.h, z.opts.threads, out)
+	default:
+		written, err = decodeBlock(z.br, z.h.version, z.h.originalSize, out)
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	if z.h.flags&flagUnknownSize == 0 && written != z.h.originalSize {
+		return 0, errSizeMismatch
+	}
+
+	if z.h.flags&flagChecksum != 0 {
+		if err := verifyChecksum(z.br, crc.Sum32()); err != nil {
+			return 0, err
+		}
+	}
+
+	return written, nil
+}
+
+// decodeBlock decodes a block of size bytes written by compressBlock, and returns the number of decoded bytes
+func decodeBlock(br *bufio.Reader, version uint8, size uint64, w io.Writer) (uint64, error) {
+	root, bitsCount, err := readBlockHeader(br, version)
+	if err != nil {
+		return 0, err
+	}
+
+	if root == nil {
+		return copyStored(br, size, w)
+	}
+
+	if root.IsLeaf {
+		return writeRun(w, root.Value, size)
+	}
+
+	return decodeBits(br, root, bitsCount, w)
+}
+
+// readBlockHeader reads the code lengths and the bits count written by compressBlock,
+// and returns the tree of the canonical codes with the bits count.
+// the tree of a block without symbols has no leaves, and the tree of a single symbol is its leaf, without bits.
+// stored blocks have no tree.
+func readBlockHeader(br *bufio.Reader, version uint8) (*node, uint64, error) {
+	symbolsCount, err := readSymbolsCount(br)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	switch symbolsCount {
+	case storedBlock:
+		return nil, 0, nil
+	case 0:
+		return &node{}, 0, nil
+	case 1:
+		value, err := br.ReadByte()
+		if err != nil {
+			return nil, 0, fmt.Errorf("failed to read symbol: %w", err)
+		}
+
+		return &node{IsLeaf: true, Value: value}, 0, nil
+	}
+
+	lengths, err := readCodeLengthPairs(br, symbolsCount)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	root, err := buildCanonicalTree(lengths)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var bitsCount uint64
+	if version == shortCountVersion {
+		bitsCount, err = readBitsCount(br)
+	} else {
+		bitsCount, err = binary.ReadUvarint(br)
+	}
+
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to read compressed data size: %w", err)
+	}
+
+	return root, bitsCount, nil
+}
+
+// decodeBits decodes the next bitsCount bits from the tree.
+// it returns the number of decoded bytes.
+func decodeBits(br *bufio.Reader, root *node, bitsCount uint64, w io.Writer) (uint64, error) {
+	written, err := root.decompress(newBitReader(br), bitsCount, w)
+	if err != nil {
+		return 0, fmt.Errorf("failed to decompress binary data: %w", err)
+	}
+
+	return written, nil
+}
+
+// copyStored copies the size bytes of a stored block from br to w, and returns size
+func copyStored(br *bufio.Reader, size uint64, w io.Writer) (uint64, error) {
+	if size > math.MaxInt64 {
+		return 0, fmt.Errorf("stored data size %d is too large: %w", size, ErrInvalidCompressedData)
+	}
+
+	_, err := io.CopyN(w, br, int64(size))
+	if errors.Is(err, io.EOF) {
+		return 0, fmt.Errorf("stored data size is less than given size: %w", ErrInvalidCompressedData)
+	}
+
+	if err != nil {
+		return 0, fmt.Errorf("failed to copy stored data: %w", err)
+	}
+
+	return size, nil
+}
+
+// writeRun writes count bytes of value to w, and returns count
+func writeRun(w io.Writer, value byte, count uint64) (uint64, error) {
+	data := bytes.Repeat([]byte{value}, int(min(count, buffSize)))
+	for left := count; left > 0; {
+		n := min(left, uint64(len(data)))
+		if _, err := w.Write(data[:n]); err != nil {
+			return 0, fmt.Errorf("write failed: %w", err)
+		}
+
+		left -= n
+	}
+
+	return count, nil
+}
+
+// verifyChecksum reads the stored checksum and compares it to the checksum of the decompressed data
+func verifyChecksum(r io.Reader, sum uint32) error {
+	stored := make([]byte, checksumSize)
+	if _, err := io.ReadFull(r, stored); err != nil {
+		return fmt.Errorf("failed to read checksum: %w", err)
+	}
+
+	if binary.BigEndian.Uint32(stored) != sum {
+		return ErrChecksum
+	}
+
+	return nil
+}
+
+// expectEOF ensures nothing comes after the end of the compressed data
+func expectEOF(br *bufio.Reader) error {
+	if _, err := br.ReadByte(); !errors.Is(err, io.EOF) {
+		return fmt.Errorf("compressed data size exceeded given size: %w", ErrInvalidCompressedData)
+	}
+
+	return nil
+}
+
+// readCodeLengths reads the code lengths written by writeCodeLengths, indexed by their symbol
+func readCodeLengths(r io.Reader) ([256]uint8, error) {
+	symbolsCount, err := readSymbolsCount(r)
+	if err != nil {
+		return [256]uint8{}, err
+	}
+
+	return readCodeLengthPairs(r, symbolsCount)
+}
+
+// readSymbolsCount reads the big endian uint16 number of symbols that starts the code lengths
+func readSymbolsCount(r io.Reader) (int, error) {
+	count := make([]byte, 2)
+	_, err := io.ReadFull(r, count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read symbols count: %w", err)
+	}
+
+	return int(binary.BigEndian.Uint16(count)), nil
+}
+
+// readCodeLengthPairs reads the value and code length pairs of symbolsCount symbols
+func readCodeLengthPairs(r io.Reader, symbolsCount int) ([256]uint8, error) {
+	lengths := [256]uint8{}
+
+	if symbolsCount > 256 {
+		return lengths, fmt.Errorf("too many symbols %d: %w", symbolsCount, ErrInvalidCompressedData)
+	}
+
+	pairs := make([]byte, 2*symbolsCount)
+	_, err := io.ReadFull(r, pairs)
+	if err != nil {
+		return lengths, fmt.Errorf("failed to read code lengths: %w", err)
+	}
+
+	for i := 0; i < len(pairs); i += 2 {
+		if lengths[pairs[i]] != 0 {
+			return lengths, fmt.Errorf("duplicate symbol '%c': %w", pairs[i], ErrInvalidCompressedData)
+		}
+
+		if pairs[i+1] == 0 {
+			return lengths, fmt.Errorf("symbol '%c' has an empty code: %w", pairs[i], ErrInvalidCompressedData)
+		}
+
+		if pairs[i+1] > maxCodeBits {
+			return lengths, fmt.Errorf("code of symbol '%c' is longer than %d bits: %w", pairs[i], maxCodeBits, ErrInvalidCompressedData)
+		}
+
+		lengths[pairs[i]] = pairs[i+1]
+	}
+
+	return lengths, nil
+}
+
+// buildCanonicalTree rebuilds the tree of the canonical codes assigned to the given code lengths
+func buildCanonicalTree(lengths [256]uint8) (*node, error) {
+	root := &node{}
+	for b, code := range canonicalCodes(lengths) {
+		if code.length == 0 {
+			continue
+		}
+
+		cur := root
+		for i := int(code.length) - 1; i >= 0; i-- {
+			if cur.IsLeaf {
+				return nil, fmt.Errorf("code of symbol '%c' overlaps another code: %w", b, ErrInvalidCompressedData)
+			}
+
+			next := &cur.Left
+			if code.code&(1<<i) != 0 {
+				next = &cur.Right
+			}
+
+			if *next == nil {
+				*next = &node{}
+			}
+
+			cur = *next
+		}
+
+		if cur.IsLeaf || cur.Left != nil || cur.Right != nil {
+			return nil, fmt.Errorf("code of symbol '%c' overlaps another code: %w", b, ErrInvalidCompressedData)
+		}
+
+		cur.IsLeaf = true
+		cur.Value = byte(b)
+	}
+
+	return root, nil
+}
+
+// readBitsCount reads a big endian uint32 bits count, as written before the bits count became a uvarint
+func readBitsCount(r io.Reader) (uint64, error) {
+	count := make([]byte, 4)
+	_, err := io.ReadFull(r, count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to read compressed data size: %w", err)
+	}
+
+	return uint64(binary.BigEndian.Uint32(count)), nil
+}
@@ -0,0 +1,67 @@
+package huffman
+
+// decodeTableBits is the number of bits looked up at once in a decode table,
+// codes longer than it continue in overflow tables.
+const decodeTableBits = 10
+
+// decodeTable maps every sequence of its bits to the code they start with.
+// codes that are longer than its bits continue in the next table of their entry.
+type decodeTable struct {
+	bits    uint8
+	entries []tableEntry
+}
+
+// tableEntry is the code starting with the bits of its index in a decode table
+type tableEntry struct {
+	// length is the number of bits of the index that are part of the code
+	length uint8
+	symbol byte
+	// invalid is set when the bits lead to a missing node, so no code starts with them
+	invalid bool
+	// next decodes the rest of a code longer than the table's bits
+	next *decodeTable
+}
+
+// newDecodeTable builds the table decoding the codes of the tree's leaves,
+// the table looks up at most bits bits, and fewer when the tree isn't as deep.
+func newDecodeTable(root *node, bits uint8) *decodeTable {
+	t := &decodeTable{bits: uint8(max(1, min(int(bits), root.height())))}
+	t.entries = make([]tableEntry, 1<<t.bits)
+
+	for i := range t.entries {
+		n := root
+		for depth := uint8(1); depth <= t.bits; depth++ {
+			next := n.Left
+			if i&(1<<(t.bits-depth)) != 0 {
+				next = n.Right
+			}
+
+			n = next
+
+			if n == nil {
+				t.entries[i] = tableEntry{length: depth, invalid: true}
+				break
+			}
+
+			if n.IsLeaf {
+				t.entries[i] = tableEntry{length: depth, symbol: n.Value}
+				break
+			}
+
+			if depth == t.bits {
+				t.entries[i] = tableEntry{length: depth, next: newDecodeTable(n, bits)}
+			}
+		}
+	}
+
+	return t
+}
+
+// height returns the length of the longest code of the tree's leaves
+func (n *node) height() int {
+	if n == nil || n.IsLeaf {
+		return 0
+	}
+
+	return 1 + max(n.Left.height(), n.Right.height())
+}
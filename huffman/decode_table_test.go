@@ -0,0 +1,220 @@
+package huffman
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewDecodeTable(t *testing.T) {
+	// a: 0, b: 10, c: 11
+	root := &node{
+		Left: &node{IsLeaf: true, Value: 'a'},
+		Right: &node{
+			Left:  &node{IsLeaf: true, Value: 'b'},
+			Right: &node{IsLeaf: true, Value: 'c'},
+		},
+	}
+
+	t.Run("whole_tree", func(t *testing.T) {
+		table := newDecodeTable(root, 10)
+		assert.Equal(t, uint8(2), table.bits)
+		assert.Equal(t, []tableEntry{
+			{length: 1, symbol: 'a'},
+			{length: 1, symbol: 'a'},
+			{length: 2, symbol: 'b'},
+			{length: 2, symbol: 'c'},
+		}, table.entries)
+	})
+
+	t.Run("overflow", func(t *testing.T) {
+		table := newDecodeTable(root, 1)
+		assert.Equal(t, uint8(1), table.bits)
+		assert.Equal(t, tableEntry{length: 1, symbol: 'a'}, table.entries[0])
+
+		next := table.entries[1].next
+		assert.Equal(t, uint8(1), table.entries[1].length)
+		assert.Equal(t, &decodeTable{bits: 1, entries: []tableEntry{
+			{length: 1, symbol: 'b'},
+			{length: 1, symbol: 'c'},
+		}}, next)
+	})
+
+	t.Run("missing_node", func(t *testing.T) {
+		table := newDecodeTable(&node{Right: &node{IsLeaf: true, Value: 'a'}}, 10)
+		assert.Equal(t, []tableEntry{
+			{length: 1, invalid: true},
+			{length: 1, symbol: 'a'},
+		}, table.entries)
+	})
+
+	t.Run("leaf_root", func(t *testing.T) {
+		table := newDecodeTable(&node{IsLeaf: true, Value: 'a'}, 10)
+		assert.Equal(t, []tableEntry{
+			{length: 1, invalid: true},
+			{length: 1, invalid: true},
+		}, table.entries)
+	})
+}
+
+func TestDecompressMatchesTreeWalk(t *testing.T) {
+	trees := map[string]*node{
+		"letters": mustBuildTree(t, randSeq(10000)),
+		// codes up to 40 bits long need several overflow tables
+		"chain": chainTree(40),
+		// only 'a' has a code, every other code is invalid
+		"incomplete": {Left: &node{Left: &node{IsLeaf: true, Value: 'a'}}},
+	}
+
+	for name, root := range trees {
+		t.Run(name, func(t *testing.T) {
+			for i := 0; i < 200; i++ {
+				bits := make([]bool, rand.Intn(500))
+				for j := range bits {
+					bits[j] = rand.Intn(4) != 0
+				}
+
+				packed := packBits(t, bits)
+				bitsCount := uint64(len(bits))
+				if i%10 == 0 {
+					// the data ends before the bits count
+					bitsCount += 16
+				}
+
+				want := &bytes.Buffer{}
+				wantWritten, wantErr := root.walkDecompress(newBitReader(bytes.NewReader(packed)), bitsCount, want)
+
+				got := &bytes.Buffer{}
+				written, err := root.decompress(newBitReader(bytes.NewReader(packed)), bitsCount, got)
+
+				assert.Equal(t, wantErr, err)
+				if err == nil {
+					assert.Equal(t, wantWritten, written)
+					assert.Equal(t, want.Bytes(), got.Bytes())
+				}
+			}
+		})
+	}
+}
+
+func TestDecompressLeavesTrailingBytes(t *testing.T) {
+	root := mustBuildTree(t, []byte("abcabcabcaaaa"))
+	compressed := &bytes.Buffer{}
+	bitsCount, err := root.compress(bytes.NewReader([]byte("abcabcabcaaaa")), compressed)
+	assert.NoError(t, err)
+
+	// the bits are followed by the next data, like a checksum
+	br := bufio.NewReader(io.MultiReader(compressed, bytes.NewReader([]byte("next"))))
+	_, err = root.decompress(newBitReader(br), bitsCount, io.Discard)
+	assert.NoError(t, err)
+
+	rest, err := io.ReadAll(br)
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("next"), rest)
+}
+
+func BenchmarkDecompress(b *testing.B) {
+	data := randSeq(1 << 20)
+	root, err := buildHuffmanTree(bytes.NewReader(data))
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	compressed := &bytes.Buffer{}
+	bitsCount, err := root.compress(bytes.NewReader(data), compressed)
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	decompressors := map[string]func(*bitReader, uint64, io.Writer) (uint64, error){
+		"table":     root.decompress,
+		"tree_walk": root.walkDecompress,
+	}
+
+	for _, name := range []string{"table", "tree_walk"} {
+		b.Run(name, func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				br := newBitReader(bytes.NewReader(compressed.Bytes()))
+				if _, err := decompressors[name](br, bitsCount, io.Discard); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// walkDecompress decodes bits by walking the tree one bit at a time,
+// it's the reference decompress is checked and benchmarked against.
+func (root *node) walkDecompress(br *bitReader, bitsCount uint64, w io.Writer) (uint64, error) {
+	bw := bufio.NewWriterSize(w, buffSize)
+
+	written := uint64(0)
+	cur := root
+	for i := uint64(0); i < bitsCount; i++ {
+		bit, err := br.readBit()
+		if errors.Is(err, io.EOF) {
+			return 0, fmt.Errorf("compressed data size is less than given size: %w", ErrInvalidCompressedData)
+		}
+
+		if err != nil {
+			return 0, fmt.Errorf("failed to read compressed data: %w", err)
+		}
+
+		next := cur.Left
+		if bit {
+			next = cur.Right
+		}
+
+		cur = next
+
+		if cur == nil {
+			return 0, fmt.Errorf("invalid char code: %w", ErrInvalidCompressedData)
+		}
+
+		if !cur.IsLeaf {
+			continue
+		}
+
+		if err := bw.WriteByte(cur.Value); err != nil {
+			return 0, fmt.Errorf("write failed: %w", err)
+		}
+
+		written++
+		cur = root
+	}
+
+	if cur != root {
+		// last bit must be a leaf
+		return 0, fmt.Errorf("incorrect last character code: %w", ErrInvalidCompressedData)
+	}
+
+	if err := bw.Flush(); err != nil {
+		return 0, fmt.Errorf("write failed: %w", err)
+	}
+
+	return written, nil
+}
+
+func mustBuildTree(t *testing.T, data []byte) *node {
+	root, err := buildHuffmanTree(bytes.NewReader(data))
+	assert.NoError(t, err)
+
+	return root
+}
+
+// chainTree returns a tree whose leaves have codes 0, 10, 110, ..., so its longest codes have depth bits
+func chainTree(depth int) *node {
+	root := &node{IsLeaf: true, Value: byte(depth)}
+	for i := depth - 1; i >= 0; i-- {
+		root = &node{Left: &node{IsLeaf: true, Value: byte(i)}, Right: root}
+	}
+
+	return root
+}
@@ -0,0 +1,346 @@
+package huffman
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadBitsCount(t *testing.T) {
+	tests := map[string]struct {
+		input    []byte
+		expected uint64
+		hasError bool
+	}{
+		"empty": {
+			input:    nil,
+			hasError: true,
+		},
+		"less_data": {
+			input:    []byte{0, 1},
+			hasError: true,
+		},
+		"valid_data": {
+			input:    []byte{0, 0, 1, 1},
+			expected: 257,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := bytes.NewReader(tc.input)
+			got, err := readBitsCount(r)
+			if tc.hasError {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	// 'a' and 'b' leaves with a one bit code each
+	withHeader := func(originalSize byte, data ...byte) []byte {
+		header := append(magic[:], formatVersion, 0, 0, 0, 0, 0, 0, 0, 0, originalSize, byte(Huffman))
+		header = append(header, 0, 2, 'a', 1, 'b', 1)
+		return append(header, data...)
+	}
+
+	withChecksum := func(sum uint32, data ...byte) []byte {
+		header := append(magic[:], formatVersion, flagChecksum, 0, 0, 0, 0, 0, 0, 0, 3, byte(Huffman))
+		header = append(header, 0, 2, 'a', 1, 'b', 1)
+		return binary.BigEndian.AppendUint32(append(header, data...), sum)
+	}
+
+	tests := map[string]struct {
+		input    []byte
+		expected []byte
+		hasError bool
+	}{
+		"empty": {
+			input:    nil,
+			hasError: true,
+		},
+		"missing_bits_count": {
+			input:    withHeader(0),
+			hasError: true,
+		},
+		"valid_data": {
+			input:    withHeader(3, 3, 64),
+			expected: []byte("aba"),
+		},
+		"valid_checksum": {
+			input:    withChecksum(crc32.ChecksumIEEE([]byte("aba")), 3, 64),
+			expected: []byte("aba"),
+		},
+		"checksum_mismatch": {
+			input:    withChecksum(crc32.ChecksumIEEE([]byte("aba")), 3, 32),
+			hasError: true,
+		},
+		"missing_checksum": {
+			input:    withChecksum(0, 3, 64)[:24],
+			hasError: true,
+		},
+		"short_count_version": {
+			input:    append(append(magic[:], shortCountVersion, 0, 0, 0, 0, 0, 0, 0, 0, 3), 0, 2, 'a', 1, 'b', 1, 0, 0, 0, 3, 64),
+			expected: []byte("aba"),
+		},
+		"varint_count_version": {
+			input:    append(append(magic[:], varintCountVersion, 0, 0, 0, 0, 0, 0, 0, 0, 3), 0, 2, 'a', 1, 'b', 1, 3, 64),
+			expected: []byte("aba"),
+		},
+		"no_symbols": {
+			input:    append(append(magic[:], formatVersion, 0, 0, 0, 0, 0, 0, 0, 0, 0, byte(Huffman)), 0, 0),
+			expected: nil,
+		},
+		"single_symbol": {
+			input:    append(append(magic[:], formatVersion, 0, 0, 0, 0, 0, 0, 0, 0, 3, byte(Huffman)), 0, 1, 'a'),
+			expected: []byte("aaa"),
+		},
+		"missing_symbol": {
+			input:    append(append(magic[:], formatVersion, 0, 0, 0, 0, 0, 0, 0, 0, 3, byte(Huffman)), 0, 1),
+			hasError: true,
+		},
+		"no_symbols_size_mismatch": {
+			input:    append(append(magic[:], formatVersion, 0, 0, 0, 0, 0, 0, 0, 0, 3, byte(Huffman)), 0, 0),
+			hasError: true,
+		},
+		"stored": {
+			input:    append(append(magic[:], formatVersion, 0, 0, 0, 0, 0, 0, 0, 0, 3, byte(Huffman)), 0xff, 0xff, 'a', 'b', 'c'),
+			expected: []byte("abc"),
+		},
+		"truncated_stored": {
+			input:    append(append(magic[:], formatVersion, 0, 0, 0, 0, 0, 0, 0, 0, 3, byte(Huffman)), 0xff, 0xff, 'a', 'b'),
+			hasError: true,
+		},
+		"truncated_bits_count": {
+			input:    withHeader(3, 128),
+			hasError: true,
+		},
+		"size_mismatch": {
+			input:    withHeader(4, 3, 64),
+			hasError: true,
+		},
+		"more_binary_data": {
+			input:    withHeader(1, 1, 192, 1),
+			hasError: true,
+		},
+		"invalid_code_lengths": {
+			input:    append(append(magic[:], formatVersion, 0, 0, 0, 0, 0, 0, 0, 0, 0, byte(Huffman)), 0, 3, 'a', 1, 'b', 1, 'c', 1, 0),
+			hasError: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := bytes.NewReader(tc.input)
+			got := &bytes.Buffer{}
+			err := decode(r, got, options{})
+			if tc.hasError {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, got.Bytes())
+		})
+	}
+}
+
+func TestDecodeCorruptedData(t *testing.T) {
+	data := randSeq(1000)
+
+	compressed := &bytes.Buffer{}
+	_, err := encode(bytes.NewReader(data), compressed, options{})
+	assert.NoError(t, err)
+
+	// flip a bit in the middle of the payload
+	corrupted := compressed.Bytes()
+	corrupted[len(corrupted)/2] ^= 1
+
+	err = decode(bytes.NewReader(corrupted), io.Discard, options{})
+	assert.ErrorIs(t, err, ErrInvalidCompressedData)
+}
+
+func TestDecodeChecksumMismatch(t *testing.T) {
+	compressed := &bytes.Buffer{}
+	_, err := encode(bytes.NewReader([]byte("abcabcabc")), compressed, options{})
+	assert.NoError(t, err)
+
+	data := compressed.Bytes()
+	data[len(data)-1] ^= 1
+
+	err = decode(bytes.NewReader(data), io.Discard, options{})
+	assert.ErrorIs(t, err, ErrChecksum)
+	assert.ErrorIs(t, err, ErrInvalidCompressedData)
+}
+
+func TestDecodeConcatenated(t *testing.T) {
+	first, second := []byte("first container, "), jsonLines(100)
+
+	compressed := &bytes.Buffer{}
+	_, err := encode(bytes.NewReader(first), compressed, options{})
+	assert.NoError(t, err)
+	_, err = encode(bytes.NewReader(second), compressed, options{method: LZ77, threads: 2})
+	assert.NoError(t, err)
+
+	got := &bytes.Buffer{}
+	err = decode(bytes.NewReader(compressed.Bytes()), got, options{})
+	assert.NoError(t, err)
+	assert.Equal(t, append(first, second...), got.Bytes())
+
+	t.Run("trailing_data", func(t *testing.T) {
+		err := decode(bytes.NewReader(append(compressed.Bytes(), "data"...)), io.Discard, options{})
+		assert.ErrorIs(t, err, ErrInvalidCompressedData)
+	})
+
+	t.Run("truncated_container", func(t *testing.T) {
+		err := decode(bytes.NewReader(append(compressed.Bytes(), magic[:]...)), io.Discard, options{})
+		assert.Error(t, err)
+	})
+}
+
+func TestDecodeCorruptedBlockSize(t *testing.T) {
+	tests := map[string]struct {
+		input []byte
+		opts  options
+	}{
+		"run": {
+			input: make([]byte, 100),
+			opts:  options{blockSize: 1000},
+		},
+		"coded": {
+			input: bytes.Repeat([]byte("aab"), 100),
+			opts:  options{blockSize: 1000},
+		},
+		"lz77": {
+			input: bytes.Repeat([]byte("abcd"), 100),
+			opts:  options{method: LZ77},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			compressed := &bytes.Buffer{}
+			_, err := encode(bytes.NewReader(tc.input), compressed, tc.opts)
+			assert.NoError(t, err)
+
+			// the first block's size is larger than the original size in the header
+			data := compressed.Bytes()
+			_, n := binary.Uvarint(data[headerSize:])
+			corrupted := binary.AppendUvarint(bytes.Clone(data[:headerSize]), 1<<32)
+			corrupted = append(corrupted, data[headerSize+n:]...)
+
+			got := &countingWriter{}
+			err = decode(bytes.NewReader(corrupted), got, options{})
+			assert.ErrorIs(t, err, ErrInvalidCompressedData)
+			assert.Zero(t, got.n)
+		})
+	}
+}
+
+func TestReadCodeLengths(t *testing.T) {
+	tests := map[string]struct {
+		input    []byte
+		expected map[byte]uint8
+		hasError bool
+	}{
+		"empty": {
+			input:    nil,
+			hasError: true,
+		},
+		"no_symbols": {
+			input:    []byte{0, 0},
+			expected: map[byte]uint8{},
+		},
+		"valid_data": {
+			input:    []byte{0, 3, 'a', 1, 'b', 2, 'c', 2},
+			expected: map[byte]uint8{'a': 1, 'b': 2, 'c': 2},
+		},
+		"too_many_symbols": {
+			input:    []byte{1, 1},
+			hasError: true,
+		},
+		"less_data": {
+			input:    []byte{0, 2, 'a', 1},
+			hasError: true,
+		},
+		"duplicate_symbol": {
+			input:    []byte{0, 2, 'a', 1, 'a', 1},
+			hasError: true,
+		},
+		"zero_length": {
+			input:    []byte{0, 2, 'a', 1, 'b', 0},
+			hasError: true,
+		},
+		"too_long": {
+			input:    []byte{0, 2, 'a', 1, 'b', maxCodeBits + 1},
+			hasError: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := bytes.NewReader(tc.input)
+			got, err := readCodeLengths(r)
+			if tc.hasError {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, lengthTable(tc.expected), got)
+		})
+	}
+}
+
+func TestBuildCanonicalTree(t *testing.T) {
+	tests := map[string]struct {
+		input    map[byte]uint8
+		expected *node
+		hasError bool
+	}{
+		"two_symbols": {
+			input:    map[byte]uint8{'b': 1, 'a': 1},
+			expected: &node{Left: &node{IsLeaf: true, Value: 'a'}, Right: &node{IsLeaf: true, Value: 'b'}},
+		},
+		"three_symbols": {
+			input: map[byte]uint8{'a': 2, 'b': 2, 'c': 1},
+			expected: &node{
+				Left: &node{IsLeaf: true, Value: 'c'},
+				Right: &node{
+					Left:  &node{IsLeaf: true, Value: 'a'},
+					Right: &node{IsLeaf: true, Value: 'b'},
+				},
+			},
+		},
+		"oversubscribed_lengths": {
+			input:    map[byte]uint8{'a': 1, 'b': 1, 'c': 1},
+			hasError: true,
+		},
+		"overlapping_codes": {
+			input:    map[byte]uint8{'a': 1, 'b': 1, 'c': 2},
+			hasError: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := buildCanonicalTree(lengthTable(tc.input))
+			if tc.hasError {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
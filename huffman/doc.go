@@ -0,0 +1,22 @@
+// Package huffman implements the piedpiper compressed format, data compressed with huffman codes in a container
+// holding its original size and a crc32 checksum.
+//
+// Like compress/gzip, a Writer is an io.WriteCloser compressing data to an underlying writer,
+// and a Reader is an io.ReadCloser decompressing data from an underlying reader:
+//
+//	zw := huffman.NewWriter(w)
+//	_, err := io.Copy(zw, r)
+//	err = zw.Close()
+//
+//	zr, err := huffman.NewReader(r)
+//	_, err = io.Copy(w, zr)
+//
+// written data is compressed in blocks as it's written, while Compress compresses a whole input,
+// and compresses inputs that can seek, like files, with a single tree:
+//
+//	_, err := huffman.Compress(w, f)
+//
+// WithMethod(LZ77) replaces strings repeated within a block by their length and distance before huffman coding,
+// and WithMethod(BWT) codes the burrows-wheeler transform of every block like bzip2.
+// a Reader gets the method from the container header.
+package huffman
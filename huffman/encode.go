@@ -0,0 +1,338 @@
+package huffman
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+const buffSize = 16 * 1024
+
+// defaultBlockSize is the block size used for inputs that can't be read twice when no block size is set
+const defaultBlockSize = 1 << 20
+
+// maxMethodBlockSize is the size of the largest block compressed with a method other than Huffman,
+// those blocks and their streams are decoded in memory, so corrupted sizes can't make the decoder allocate more.
+const maxMethodBlockSize = 16 << 20
+
+var errInputChanged = errors.New("input changed while compressing")
+
+// storedBlock replaces the symbols count of a block whose bytes follow as they are
+const storedBlock = 0xffff
+
+// options configures how encode compresses data
+type options struct {
+	// blockSize splits the input into blocks of at most blockSize bytes, each compressed with its own tree.
+	// the whole input is compressed with a single tree when it's zero, unless it can't seek.
+	blockSize int
+	// threads is the number of blocks compressed or decompressed concurrently, blocks are processed one at a time when it's zero.
+	threads int
+	// adaptive codes the input in a single pass with codes updated as bytes are seen, instead of blocks or trees.
+	adaptive bool
+	// maxCodeLength limits the length of the codes of trees, it's defaultCodeLengthLimit when it's zero.
+	maxCodeLength int
+	// method transforms blocks before they're huffman coded, methods other than Huffman always compress in blocks.
+	method Method
+}
+
+// Method is how data is compressed, before it's coded with huffman codes
+type Method uint8
+
+const (
+	// Huffman codes the bytes of the data as they are
+	Huffman Method = iota
+	// LZ77 replaces strings repeated within the last 32KiB with their length and distance,
+	// which compresses text and structured data like JSON much better, but more slowly.
+	LZ77
+	// BWT sorts the rotations of every block, the burrows-wheeler transform, so bytes seen in similar contexts end up together,
+	// then codes the runs of their move-to-front indexes like bzip2, which compresses text best but most slowly.
+	BWT
+
+	maxMethod = BWT
+)
+
+// Option configures a Writer or a Reader
+type Option func(*options)
+
+// WithBlockSize makes a Writer split the data into blocks of at most size bytes, each compressed with its own tree.
+// data that can't seek is compressed in blocks of 1MiB when no block size is set.
+// blocks of methods other than Huffman are at most 16MiB.
+func WithBlockSize(size int) Option {
+	return func(o *options) {
+		o.blockSize = size
+	}
+}
+
+// WithThreads sets the number of blocks compressed or decompressed concurrently, blocks are processed one at a time by default
+func WithThreads(threads int) Option {
+	return func(o *options) {
+		o.threads = threads
+	}
+}
+
+// WithAdaptive makes a Writer compress the data in a single pass with adaptive huffman codes, without storing a tree.
+// Unlike the other modes it never falls back to storing data that doesn't compress, so such data grows slightly.
+func WithAdaptive() Option {
+	return func(o *options) {
+		o.adaptive = true
+	}
+}
+
+// WithMaxCodeLength limits the codes of a Writer's trees to length bits, 15 by default.
+// it must be between 8 and 32, shorter codes decode faster but may compress a little less.
+func WithMaxCodeLength(length int) Option {
+	return func(o *options) {
+		o.maxCodeLength = length
+	}
+}
+
+// WithMethod sets the compression method of a Writer, Huffman by default.
+// a Reader gets the method of the data from its header.
+func WithMethod(method Method) Option {
+	return func(o *options) {
+		o.method = method
+	}
+}
+
+func newOptions(opts []Option) options {
+	o := options{}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// encode compresses data read from r and writes it to w, and returns the number of bytes read from r.
+// without blocks, r is read twice, once to build the huffman tree and once to write the prefix codes,
+// so inputs that can't seek, like pipes, are always compressed in blocks.
+func encode(r io.Reader, w io.Writer, opts options) (uint64, error) {
+	if opts.method > maxMethod {
+		return 0, fmt.Errorf("unknown compression method %d", opts.method)
+	}
+
+	if opts.adaptive {
+		if opts.method != Huffman {
+			return 0, errors.New("adaptive codes only support the huffman method")
+		}
+
+		return encodeAdaptive(r, w)
+	}
+
+	limit, err := opts.codeLengthLimit()
+	if err != nil {
+		return 0, err
+	}
+
+	start, err := currentOffset(r)
+	if err != nil || opts.blockSize > 0 || opts.method != Huffman {
+		if opts.blockSize <= 0 {
+			opts.blockSize = defaultBlockSize
+		}
+
+		if opts.method != Huffman && opts.blockSize > maxMethodBlockSize {
+			return 0, fmt.Errorf("block size %d is larger than %d, the largest block size of compression method %d", opts.blockSize, maxMethodBlockSize, opts.method)
+		}
+
+		return encodeBlocks(r, w, opts.blockSize, opts.threads, opts.method, limit)
+	}
+
+	rs := r.(io.ReadSeeker)
+
+	// build huffman tree
+	tree, err := buildHuffmanTree(r)
+	if err != nil {
+		return 0, fmt.Errorf("failed to build huffman tree: %w", err)
+	}
+
+	// write container header, the original size is the root's frequency
+	// write compressed data
+	// write checksum of the compressed bytes
+
+	h := header{
+		version:      formatVersion,
+		flags:        flagChecksum,
+		originalSize: tree.Frequency,
+	}
+
+	if err := writeHeader(w, h); err != nil {
+		return 0, fmt.Errorf("failed to write header: %w", err)
+	}
+
+	_, err = rs.Seek(start, io.SeekStart)
+	if err != nil {
+		return 0, err
+	}
+
+	crc := crc32.NewIEEE()
+	if err := compressBlock(tree, limit, io.TeeReader(rs, crc), w); err != nil {
+		return 0, err
+	}
+
+	if _, err := w.Write(crc.Sum(nil)); err != nil {
+		return 0, fmt.Errorf("failed to write checksum: %w", err)
+	}
+
+	return tree.Frequency, nil
+}
+
+// currentOffset returns the offset r is read from, it fails if r can't seek
+func currentOffset(r io.Reader) (int64, error) {
+	s, ok := r.(io.Seeker)
+	if !ok {
+		return 0, errors.New("input can't seek")
+	}
+
+	return s.Seek(0, io.SeekCurrent)
+}
+
+// inputSize returns the number of bytes left to read from r, it fails if r can't seek
+func inputSize(r io.Reader) (uint64, error) {
+	start, err := currentOffset(r)
+	if err != nil {
+		return 0, err
+	}
+
+	s := r.(io.Seeker)
+	end, err := s.Seek(0, io.SeekEnd)
+	if err != nil {
+		return 0, err
+	}
+
+	if _, err := s.Seek(start, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	return uint64(end - start), nil
+}
+
+// compressBlock writes the tree's code lengths and the bits count, known ahead from the tree's frequencies,
+// followed by the prefix codes of data read from r, which must be the data the tree was built from.
+// codes are limited to limit bits.
+// an empty block is written as no symbols, and a block of a single symbol as the symbol alone,
+// since the block's size is known ahead, like the original size.
+// a block whose codes wouldn't be smaller than its bytes, like random data, is stored as it is.
+func compressBlock(tree *node, limit uint8, r io.Reader, w io.Writer) error {
+	if tree.Frequency == 0 || tree.IsLeaf {
+		header := []byte{0, 0}
+		if tree.IsLeaf {
+			header = []byte{0, 1, tree.Value}
+		}
+
+		if _, err := w.Write(header); err != nil {
+			return fmt.Errorf("failed to write block header: %w", err)
+		}
+
+		return readRun(r, tree.Value, tree.Frequency)
+	}
+
+	tree, err := tree.limitCodeLengths(limit)
+	if err != nil {
+		return fmt.Errorf("failed to limit code lengths: %w", err)
+	}
+
+	// write code lengths, the decoder rebuilds canonical codes from them
+	// write bits count
+	// use tree to write bytes
+
+	buf := &bytes.Buffer{}
+	writeCodeLengths(buf, tree.codeLengths())
+
+	bitsCount := tree.bitsCount()
+	buf.Write(binary.AppendUvarint(nil, bitsCount))
+
+	if uint64(buf.Len())+(bitsCount+7)/8 >= 2+tree.Frequency {
+		return storeBlock(tree.Frequency, r, w)
+	}
+
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write block header: %w", err)
+	}
+
+	written, err := tree.compress(r, w)
+	if err != nil {
+		return err
+	}
+
+	if written != bitsCount {
+		return errInputChanged
+	}
+
+	return nil
+}
+
+// storeBlock writes the stored block marker followed by the size bytes read from r
+func storeBlock(size uint64, r io.Reader, w io.Writer) error {
+	if _, err := w.Write(binary.BigEndian.AppendUint16(nil, storedBlock)); err != nil {
+		return fmt.Errorf("failed to write block header: %w", err)
+	}
+
+	n, err := io.Copy(w, r)
+	if err != nil {
+		return fmt.Errorf("failed to write stored data: %w", err)
+	}
+
+	if uint64(n) != size {
+		return errInputChanged
+	}
+
+	return nil
+}
+
+// readRun reads r until EOF, and fails unless it reads count bytes of value, like when r changed since the tree was built
+func readRun(r io.Reader, value byte, count uint64) error {
+	data := make([]byte, buffSize)
+	read := uint64(0)
+	for {
+		n, err := r.Read(data)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+
+		for _, b := range data[:n] {
+			if b != value {
+				return errInputChanged
+			}
+		}
+
+		read += uint64(n)
+
+		if errors.Is(err, io.EOF) {
+			break
+		}
+	}
+
+	if read != count {
+		return errInputChanged
+	}
+
+	return nil
+}
+
+// writeCodeLengths writes the number of symbols as a big endian uint16,
+// followed by a value and code length pair for every symbol in ascending order of values.
+// symbols with a zero length have no code, and aren't written.
+func writeCodeLengths(buf *bytes.Buffer, lengths [256]uint8) {
+	symbolsCount := 0
+	for _, length := range lengths {
+		if length != 0 {
+			symbolsCount++
+		}
+	}
+
+	count := make([]byte, 2)
+	binary.BigEndian.PutUint16(count, uint16(symbolsCount))
+	buf.Write(count)
+
+	for b, length := range lengths {
+		if length == 0 {
+			continue
+		}
+
+		buf.WriteByte(byte(b))
+		buf.WriteByte(length)
+	}
+}
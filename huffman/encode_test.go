@@ -0,0 +1,405 @@
+package huffman
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math/rand"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var letters = []byte("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ!@#$%^&*()_+-={}[]:'\";/?.>,<\\|`~")
+
+func randSeq(n int) []byte {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = letters[rand.Intn(len(letters))]
+	}
+	return b
+}
+
+func TestEncode(t *testing.T) {
+	tests := map[string]struct {
+		input    []byte
+		block    []byte
+		hasError bool
+	}{
+		"empty": {
+			input: nil,
+			// no symbols
+			block: []byte{0, 0},
+		},
+		"single_character": {
+			input: []byte("a"),
+			// a run of 'a'
+			block: []byte{0, 1, 'a'},
+		},
+		"multiple_same_character": {
+			input: []byte("aaaa"),
+			block: []byte{0, 1, 'a'},
+		},
+		"multiple_differenct_characters": {
+			input: []byte("aaaaaaaaaaaaaaab"),
+			// 'a' is coded as 0 and 'b' as 1
+			block: []byte{0, 2, 'a', 1, 'b', 1, 16, 0, 1},
+		},
+		"stored": {
+			input: []byte("abca"),
+			// the codes and their lengths are larger than the input
+			block: []byte{0xff, 0xff, 'a', 'b', 'c', 'a'},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			buf := &bytes.Buffer{}
+			err := writeHeader(buf, header{version: formatVersion, flags: flagChecksum, originalSize: uint64(len(tc.input))})
+			assert.NoError(t, err)
+
+			want := append(buf.Bytes(), tc.block...)
+			want = binary.BigEndian.AppendUint32(want, crc32.ChecksumIEEE(tc.input))
+
+			r := bytes.NewReader(tc.input)
+			got := &bytes.Buffer{}
+			_, err = encode(r, got, options{})
+			assert.NoError(t, err)
+			assert.Equal(t, want, got.Bytes())
+		})
+	}
+}
+
+func TestWriteCodeLengths(t *testing.T) {
+	tests := map[string]struct {
+		input    map[byte]uint8
+		expected []byte
+	}{
+		"empty": {
+			input:    map[byte]uint8{},
+			expected: []byte{0, 0},
+		},
+		"ordered_by_value": {
+			input:    map[byte]uint8{'c': 2, 'a': 1, 'b': 2},
+			expected: []byte{0, 3, 'a', 1, 'b', 2, 'c', 2},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := &bytes.Buffer{}
+			writeCodeLengths(got, lengthTable(tc.input))
+			assert.Equal(t, tc.expected, got.Bytes())
+		})
+	}
+}
+
+func TestEncodeDecode(t *testing.T) {
+	for i := 1; i <= 10000000; i *= 10 {
+		str := randSeq(i)
+		r := bytes.NewReader(str)
+
+		compressed := &bytes.Buffer{}
+		_, err := encode(r, compressed, options{})
+		assert.NoError(t, err)
+		fmt.Printf("compressed data length: %d\n", compressed.Len())
+
+		got := bytes.NewBuffer(make([]byte, 0, len(str)))
+		w := io.Writer(got)
+
+		err = decode(compressed, w, options{})
+		assert.NoError(t, err)
+
+		assert.Equal(t, str, got.Bytes())
+	}
+}
+
+func TestEncodeDecodeSingleSymbol(t *testing.T) {
+	tests := map[string]struct {
+		input []byte
+		opts  options
+	}{
+		"empty": {
+			input: nil,
+		},
+		"empty_blocks": {
+			input: nil,
+			opts:  options{blockSize: 1024},
+		},
+		"zeros": {
+			input: make([]byte, 1<<20),
+		},
+		"zeros_blocks": {
+			input: make([]byte, 1<<20),
+			opts:  options{blockSize: 1 << 18, threads: 4},
+		},
+		"single_byte": {
+			input: []byte("a"),
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			compressed := &bytes.Buffer{}
+			_, err := encode(bytes.NewReader(tc.input), compressed, tc.opts)
+			assert.NoError(t, err)
+
+			// a run is stored as its symbol, whatever its size, at most 4 blocks of a 3 bytes size and a 3 bytes header
+			assert.LessOrEqual(t, compressed.Len(), headerSize+4*(3+3)+1+checksumSize)
+
+			got := &bytes.Buffer{}
+			err = decode(compressed, got, tc.opts)
+			assert.NoError(t, err)
+			assert.Equal(t, len(tc.input), got.Len())
+			assert.True(t, bytes.Equal(tc.input, got.Bytes()))
+		})
+	}
+}
+
+func TestEncodeDecodeIncompressible(t *testing.T) {
+	input := make([]byte, 100000)
+	rand.New(rand.NewSource(1)).Read(input)
+
+	tests := map[string]struct {
+		opts options
+		// overhead is the number of bytes added to the input
+		overhead int
+	}{
+		"single_tree": {
+			overhead: headerSize + 2 + checksumSize,
+		},
+		"blocks": {
+			opts: options{blockSize: 10000, threads: 4},
+			// 10 blocks of a 2 bytes size and a stored block marker, and the end of blocks
+			overhead: headerSize + 10*(2+2) + 1 + checksumSize,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			compressed := &bytes.Buffer{}
+			_, err := encode(bytes.NewReader(input), compressed, tc.opts)
+			assert.NoError(t, err)
+			assert.Equal(t, len(input)+tc.overhead, compressed.Len())
+
+			got := &bytes.Buffer{}
+			err = decode(compressed, got, tc.opts)
+			assert.NoError(t, err)
+			assert.True(t, bytes.Equal(input, got.Bytes()))
+		})
+	}
+}
+
+func TestCompressBlockRunChanged(t *testing.T) {
+	tree, err := buildHuffmanTree(bytes.NewReader([]byte("aaaa")))
+	assert.NoError(t, err)
+
+	for _, input := range []string{"aaab", "aaa", "aaaaa"} {
+		err := compressBlock(tree, defaultCodeLengthLimit, strings.NewReader(input), io.Discard)
+		assert.ErrorIs(t, err, errInputChanged, input)
+	}
+}
+
+func TestEncodeDeterministic(t *testing.T) {
+	// many symbols of equal frequencies lead to many ties while building trees
+	input := bytes.Repeat([]byte("abcdefghijklmnopqrstuvwxyz0123456789"), 1000)
+	input = append(input, randSeq(10000)...)
+
+	tests := map[string]options{
+		"single_tree":       {},
+		"sequential_blocks": {blockSize: 1000, threads: 1},
+		"concurrent_blocks": {blockSize: 1000, threads: 8},
+		"short_codes":       {maxCodeLength: minCodeLengthLimit},
+		"adaptive":          {adaptive: true},
+	}
+
+	hashes := map[string][sha256.Size]byte{}
+	for name, opts := range tests {
+		t.Run(name, func(t *testing.T) {
+			for i := 0; i < 20; i++ {
+				compressed := &bytes.Buffer{}
+				_, err := encode(bytes.NewReader(input), compressed, opts)
+				assert.NoError(t, err)
+
+				hash := sha256.Sum256(compressed.Bytes())
+				if i == 0 {
+					hashes[name] = hash
+					continue
+				}
+
+				assert.Equal(t, hashes[name], hash)
+			}
+		})
+	}
+
+	// the number of threads doesn't change the output
+	assert.Equal(t, hashes["sequential_blocks"], hashes["concurrent_blocks"])
+}
+
+func TestEncodeNonSeekable(t *testing.T) {
+	str := randSeq(100000)
+
+	t.Run("reader", func(t *testing.T) {
+		// hide bytes.Reader's Seek method
+		r := struct{ io.Reader }{bytes.NewReader(str)}
+
+		compressed := &bytes.Buffer{}
+		read, err := encode(r, compressed, options{})
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(len(str)), read)
+
+		h, err := readHeader(bytes.NewReader(compressed.Bytes()))
+		assert.NoError(t, err)
+		assert.Equal(t, flagChecksum|flagBlocks|flagUnknownSize, h.flags)
+
+		got := bytes.NewBuffer(make([]byte, 0, len(str)))
+		err = decode(compressed, got, options{})
+		assert.NoError(t, err)
+		assert.Equal(t, str, got.Bytes())
+	})
+
+	t.Run("pipe", func(t *testing.T) {
+		// pipes are files that fail to seek
+		pr, pw, err := os.Pipe()
+		assert.NoError(t, err)
+		defer pr.Close()
+
+		go func() {
+			_, _ = pw.Write(str)
+			pw.Close()
+		}()
+
+		compressed := &bytes.Buffer{}
+		_, err = encode(pr, compressed, options{})
+		assert.NoError(t, err)
+
+		got := bytes.NewBuffer(make([]byte, 0, len(str)))
+		err = decode(compressed, got, options{})
+		assert.NoError(t, err)
+		assert.Equal(t, str, got.Bytes())
+	})
+}
+
+func TestEncodeFromOffset(t *testing.T) {
+	for _, blockSize := range []int{0, 10} {
+		r := bytes.NewReader([]byte("skipped data"))
+		_, err := r.Seek(8, io.SeekStart)
+		assert.NoError(t, err)
+
+		compressed := &bytes.Buffer{}
+		read, err := encode(r, compressed, options{blockSize: blockSize})
+		assert.NoError(t, err)
+		assert.Equal(t, uint64(4), read)
+
+		got := &bytes.Buffer{}
+		err = decode(compressed, got, options{})
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("data"), got.Bytes())
+	}
+}
+
+// patternReader yields size bytes repeating pattern, without holding them in memory
+type patternReader struct {
+	pattern []byte
+	size    int64
+	offset  int64
+}
+
+func (p *patternReader) Read(b []byte) (int, error) {
+	if p.offset >= p.size {
+		return 0, io.EOF
+	}
+
+	if remaining := p.size - p.offset; int64(len(b)) > remaining {
+		b = b[:remaining]
+	}
+
+	for i := range b {
+		b[i] = p.pattern[(p.offset+int64(i))%int64(len(p.pattern))]
+	}
+
+	p.offset += int64(len(b))
+
+	return len(b), nil
+}
+
+func (p *patternReader) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += p.offset
+	case io.SeekEnd:
+		offset += p.size
+	}
+
+	if offset < 0 {
+		return 0, errors.New("negative offset")
+	}
+
+	p.offset = offset
+
+	return offset, nil
+}
+
+// countingWriter discards written data, only counting its size
+type countingWriter struct {
+	n uint64
+}
+
+func (c *countingWriter) Write(p []byte) (int, error) {
+	c.n += uint64(len(p))
+	return len(p), nil
+}
+
+// prefixWriter writes to w, keeping the first n bytes written
+type prefixWriter struct {
+	w      io.Writer
+	n      int
+	prefix []byte
+}
+
+func (p *prefixWriter) Write(b []byte) (int, error) {
+	p.prefix = append(p.prefix, b[:min(len(b), p.n-len(p.prefix))]...)
+	return p.w.Write(b)
+}
+
+func TestEncodeDecodeOverflowingBitsCount(t *testing.T) {
+	if testing.Short() || os.Getenv("PIED_PIPER_LONG_TESTS") == "" {
+		t.Skip("compresses 512 MiB of data, set PIED_PIPER_LONG_TESTS=1 to run it")
+	}
+
+	// 0 is twice as frequent as the other byte values but 255, so its code is 7 bits long and the others are 8 bits long,
+	// 2046 bits for every 256 bytes, which is just smaller than storing them so the data is still coded.
+	// the compressed data is 2^32+2042 bits long, more than fits in a uint32 bits count
+	pattern := make([]byte, 256)
+	for i := 1; i < len(pattern); i++ {
+		pattern[i] = byte(i - 1)
+	}
+
+	size := int64(len(pattern)) * (1<<32/2046 + 1)
+
+	pr, pw := io.Pipe()
+	head := &prefixWriter{w: pw, n: 1024}
+	go func() {
+		_, err := encode(&patternReader{pattern: pattern, size: size}, head, options{})
+		pw.CloseWithError(err)
+	}()
+
+	// decode verifies the original size and checksum
+	got := &countingWriter{}
+	err := decode(pr, got, options{})
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(size), got.n)
+
+	// the block is coded instead of stored, with a bits count larger than a uint32
+	root, bitsCount, err := readBlockHeader(bufio.NewReader(bytes.NewReader(head.prefix[headerSize:])), formatVersion)
+	assert.NoError(t, err)
+	assert.NotNil(t, root)
+	assert.Greater(t, bitsCount, uint64(1<<32))
+}
@@ -0,0 +1,258 @@
+package huffman
+
+import (
+	"bufio"
+	"container/heap"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// maxCodeBits is the length of the longest code, so codes fit in 64 bits.
+// huffman codes only get longer for inputs of more than 10^13 bytes, whose frequencies grow like fibonacci numbers.
+const maxCodeBits = 64
+
+// ErrInvalidCompressedData is wrapped by the errors returned when decompressing data that is corrupted or not piedpiper compressed data
+var ErrInvalidCompressedData = errors.New("invalid compressed data")
+
+type node struct {
+	Frequency uint64 `json:"fr,omitempty"`
+	Left      *node  `json:"l,omitempty"`
+	Right     *node  `json:"r,omitempty"`
+	IsLeaf    bool   `json:"ilf,omitempty"`
+	Value     byte   `json:"v,omitempty"`
+}
+
+// this needs to use a heap for nodes
+func buildHuffmanTree(r io.Reader) (*node, error) {
+	if r == nil {
+		return nil, nil
+	}
+
+	byteFrequency := [256]uint64{}
+	buff := make([]byte, buffSize)
+	for {
+		n, err := r.Read(buff)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return nil, err
+		}
+
+		for _, b := range buff[:n] {
+			byteFrequency[b]++
+		}
+
+		if errors.Is(err, io.EOF) {
+			break
+		}
+	}
+
+	nodes := &nodeHeap{}
+	for b, freq := range byteFrequency {
+		if freq == 0 {
+			continue
+		}
+
+		heap.Push(nodes, node{
+			Frequency: freq,
+			IsLeaf:    true,
+			Value:     byte(b),
+		})
+	}
+
+	// the tree of an empty input has no leaves
+	if nodes.Len() == 0 {
+		return &node{}, nil
+	}
+
+	// the tree of a single symbol is its leaf
+	for {
+		if nodes.Len() == 1 {
+			break
+		}
+
+		n1 := heap.Pop(nodes).(node)
+		n2 := heap.Pop(nodes).(node)
+		heap.Push(nodes, node{
+			Left:      &n1,
+			Right:     &n2,
+			Frequency: n1.Frequency + n2.Frequency,
+			Value:     min(n1.Value, n2.Value),
+		})
+	}
+
+	return &(*nodes)[0], nil
+}
+
+// codeLengths returns the depth of every leaf in the tree, which is the length of its prefix code,
+// indexed by the leaf's byte. bytes without a leaf have a zero length.
+func (n *node) codeLengths() [256]uint8 {
+	lengths := [256]uint8{}
+
+	explore(n, 0, &lengths)
+
+	return lengths
+}
+
+// explore explores tree nodes, while assigning their depth to leaf nodes in the lengths table
+func explore(n *node, depth uint8, lengths *[256]uint8) {
+	if n == nil {
+		return
+	}
+
+	if n.IsLeaf {
+		lengths[n.Value] = depth
+		return
+	}
+
+	explore(n.Left, depth+1, lengths)
+	explore(n.Right, depth+1, lengths)
+}
+
+// buildPrefixCodeTable assigns canonical huffman codes to the tree's leaves.
+// canonical codes only depend on code lengths, so a decoder can rebuild them from the lengths alone.
+func (n *node) buildPrefixCodeTable() [256]prefixCode {
+	return canonicalCodes(n.codeLengths())
+}
+
+// prefixCode is a code of length bits, stored in the least significant bits of code
+type prefixCode struct {
+	code   uint64
+	length uint8
+}
+
+// canonicalCodes assigns consecutive codes to symbols ordered by code length then by value,
+// appending zeros to the code whenever the length increases.
+// codes are indexed by their symbol, symbols with a zero length have no code.
+// lengths must be at most maxCodeBits, so codes fit in a prefixCode.
+func canonicalCodes(lengths [256]uint8) [256]prefixCode {
+	codes := [256]prefixCode{}
+	code := prefixCode{}
+	for length := uint8(1); length <= maxCodeBits; length++ {
+		code.code <<= 1
+		code.length = length
+
+		for b := range lengths {
+			if lengths[b] != length {
+				continue
+			}
+
+			codes[b] = code
+			code.code++
+		}
+	}
+
+	return codes
+}
+
+// bitsCount returns the number of bits needed to encode the data the tree was built from
+func (n *node) bitsCount() uint64 {
+	return countBits(n, 0)
+}
+
+func countBits(n *node, depth uint64) uint64 {
+	if n == nil {
+		return 0
+	}
+
+	if n.IsLeaf {
+		return n.Frequency * depth
+	}
+
+	return countBits(n.Left, depth+1) + countBits(n.Right, depth+1)
+}
+
+// compress writes the prefix codes of all bytes read from r to w, and returns the number of written bits
+func (n *node) compress(r io.Reader, w io.Writer) (uint64, error) {
+	if n.height() > maxCodeBits {
+		return 0, fmt.Errorf("codes are longer than %d bits", maxCodeBits)
+	}
+
+	prefixCodeTable := n.buildPrefixCodeTable()
+
+	bw := newBitWriter(w)
+	data := make([]byte, buffSize)
+	for {
+		n, err := r.Read(data)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return 0, err
+		}
+
+		for i := 0; i < n; i++ {
+			code := prefixCodeTable[data[i]]
+			if code.length == 0 {
+				// should never happen
+				return 0, fmt.Errorf("byte '%c' is not found in prefix code table", data[i])
+			}
+
+			if err := bw.writeBits(code.code, code.length); err != nil {
+				return 0, fmt.Errorf("failed to write compressed data: %w", err)
+			}
+		}
+
+		if errors.Is(err, io.EOF) {
+			break
+		}
+	}
+
+	if err := bw.flush(); err != nil {
+		return 0, fmt.Errorf("failed to write compressed data: %w", err)
+	}
+
+	return bw.count, nil
+}
+
+// decompress decodes bitsCount bits read from br using the tree, and writes the decoded bytes to w.
+// codes are looked up in decode tables several bits at a time, instead of walking the tree one bit at a time.
+// it returns the number of decoded bytes.
+func (root *node) decompress(br *bitReader, bitsCount uint64, w io.Writer) (uint64, error) {
+	table := newDecodeTable(root, decodeTableBits)
+	bw := bufio.NewWriterSize(w, buffSize)
+
+	// the bits end within the last byte of the compressed data, whatever follows it is left unread
+	limit := br.read + (bitsCount-min(bitsCount, uint64(br.nbits))+7)/8
+
+	written := uint64(0)
+	for left := bitsCount; left > 0; {
+		t := table
+		for {
+			if err := br.fill(limit); err != nil && !errors.Is(err, io.EOF) {
+				return 0, fmt.Errorf("failed to read compressed data: %w", err)
+			}
+
+			e := t.entries[br.peek(t.bits)]
+			if uint64(e.length) > min(left, uint64(br.nbits)) {
+				if uint64(br.nbits) < left {
+					return 0, fmt.Errorf("compressed data size is less than given size: %w", ErrInvalidCompressedData)
+				}
+
+				// last bit must be a leaf
+				return 0, fmt.Errorf("incorrect last character code: %w", ErrInvalidCompressedData)
+			}
+
+			if e.invalid {
+				return 0, fmt.Errorf("invalid char code: %w", ErrInvalidCompressedData)
+			}
+
+			br.consume(e.length)
+			left -= uint64(e.length)
+
+			if e.next != nil {
+				t = e.next
+				continue
+			}
+
+			if err := bw.WriteByte(e.symbol); err != nil {
+				return 0, fmt.Errorf("write failed: %w", err)
+			}
+
+			written++
+			break
+		}
+	}
+
+	if err := bw.Flush(); err != nil {
+		return 0, fmt.Errorf("write failed: %w", err)
+	}
+
+	return written, nil
+}
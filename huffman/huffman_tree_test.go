@@ -1,8 +1,9 @@
-package main
+package huffman
 
 import (
 	"bytes"
 	"io"
+	"math/rand"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -14,41 +15,22 @@ func TestBuildFrequencyTree(t *testing.T) {
 		expected *node
 	}{
 		"empty": {
-			input: "",
-			expected: &node{
-				Frequency: 0,
-				Left: &node{
-					Frequency: 0,
-					IsLeaf:    true,
-					Value:     'a',
-				},
-				Right: &node{
-					Frequency: 0,
-					IsLeaf:    true,
-					Value:     'b',
-				},
-			},
+			input:    "",
+			expected: &node{},
 		},
 		"same_char": {
 			input: "aaaa",
 			expected: &node{
 				Frequency: 4,
-				Left: &node{
-					Frequency: 0,
-					IsLeaf:    true,
-					Value:     'b',
-				},
-				Right: &node{
-					Frequency: 4,
-					IsLeaf:    true,
-					Value:     'a',
-				},
+				IsLeaf:    true,
+				Value:     'a',
 			},
 		},
 		"mixed_chars": {
 			input: "abababaa",
 			expected: &node{
 				Frequency: 8,
+				Value:     'a',
 				Left: &node{
 					Frequency: 3,
 					IsLeaf:    true,
@@ -65,6 +47,7 @@ func TestBuildFrequencyTree(t *testing.T) {
 			input: "ababab",
 			expected: &node{
 				Frequency: 6,
+				Value:     'a',
 				Left: &node{
 					Frequency: 3,
 					IsLeaf:    true,
@@ -81,8 +64,10 @@ func TestBuildFrequencyTree(t *testing.T) {
 			input: "abbcccddddeeeee",
 			expected: &node{
 				Frequency: 15,
+				Value:     'a',
 				Left: &node{
 					Frequency: 6,
+					Value:     'a',
 					Left: &node{
 						Frequency: 3,
 						IsLeaf:    true,
@@ -90,6 +75,7 @@ func TestBuildFrequencyTree(t *testing.T) {
 					},
 					Right: &node{
 						Frequency: 3,
+						Value:     'a',
 						Left: &node{
 							Frequency: 1,
 							IsLeaf:    true,
@@ -104,6 +90,7 @@ func TestBuildFrequencyTree(t *testing.T) {
 				},
 				Right: &node{
 					Frequency: 9,
+					Value:     'd',
 					Left: &node{
 						Frequency: 4,
 						IsLeaf:    true,
@@ -159,7 +146,8 @@ func TestDecompress(t *testing.T) {
 			t.Run(name, func(t *testing.T) {
 				got := bytes.NewBuffer(make([]byte, 0, len(tc.expected)))
 				w := io.Writer(got)
-				err := root.decompress(tc.input, w)
+				br := newBitReader(bytes.NewReader(packBits(t, tc.input)))
+				written, err := root.decompress(br, uint64(len(tc.input)), w)
 				if tc.hasError {
 					assert.Error(t, err)
 					return
@@ -167,6 +155,7 @@ func TestDecompress(t *testing.T) {
 
 				assert.NoError(t, err)
 				assert.Equal(t, tc.expected, got.Bytes())
+				assert.Equal(t, uint64(len(tc.expected)), written)
 			})
 		}
 
@@ -215,7 +204,7 @@ func TestDecompress(t *testing.T) {
 				expected: []byte("abc"),
 				hasError: false,
 			},
-			"invalid_char": {
+			"incomplete_char": {
 				input:    []bool{true},
 				hasError: true,
 			},
@@ -225,7 +214,8 @@ func TestDecompress(t *testing.T) {
 			t.Run(name, func(t *testing.T) {
 				got := bytes.NewBuffer(make([]byte, 0, len(tc.expected)))
 				w := io.Writer(got)
-				err := root.decompress(tc.input, w)
+				br := newBitReader(bytes.NewReader(packBits(t, tc.input)))
+				written, err := root.decompress(br, uint64(len(tc.input)), w)
 				if tc.hasError {
 					assert.Error(t, err)
 					return
@@ -233,6 +223,7 @@ func TestDecompress(t *testing.T) {
 
 				assert.NoError(t, err)
 				assert.Equal(t, tc.expected, got.Bytes())
+				assert.Equal(t, uint64(len(tc.expected)), written)
 			})
 		}
 	})
@@ -241,11 +232,11 @@ func TestDecompress(t *testing.T) {
 func TestBuildPrefixCodeTable(t *testing.T) {
 	tests := map[string]struct {
 		input    *node
-		expected map[byte][]bool
+		expected map[byte]prefixCode
 	}{
 		"nil_tree": {
 			input:    nil,
-			expected: map[byte][]bool{},
+			expected: map[byte]prefixCode{},
 		},
 		"two_characters": {
 			input: &node{Frequency: 10,
@@ -260,9 +251,9 @@ func TestBuildPrefixCodeTable(t *testing.T) {
 					Value:     'b',
 				},
 			},
-			expected: map[byte][]bool{
-				'a': {false},
-				'b': {true},
+			expected: map[byte]prefixCode{
+				'a': {code: 0b0, length: 1},
+				'b': {code: 0b1, length: 1},
 			},
 		},
 		"three_characters": {
@@ -286,10 +277,10 @@ func TestBuildPrefixCodeTable(t *testing.T) {
 					},
 				},
 			},
-			expected: map[byte][]bool{
-				'a': {false},
-				'b': {true, false},
-				'c': {true, true},
+			expected: map[byte]prefixCode{
+				'a': {code: 0b0, length: 1},
+				'b': {code: 0b10, length: 2},
+				'c': {code: 0b11, length: 2},
 			},
 		},
 		"four_characters": {
@@ -321,11 +312,11 @@ func TestBuildPrefixCodeTable(t *testing.T) {
 					},
 				},
 			},
-			expected: map[byte][]bool{
-				'z': {false, false},
-				'x': {false, true},
-				'b': {true, false},
-				'c': {true, true},
+			expected: map[byte]prefixCode{
+				'b': {code: 0b00, length: 2},
+				'c': {code: 0b01, length: 2},
+				'x': {code: 0b10, length: 2},
+				'z': {code: 0b11, length: 2},
 			},
 		},
 	}
@@ -333,7 +324,44 @@ func TestBuildPrefixCodeTable(t *testing.T) {
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
 			got := tc.input.buildPrefixCodeTable()
-			assert.Equal(t, tc.expected, got)
+			assert.Equal(t, codeTable(tc.expected), got)
+		})
+	}
+}
+
+func TestCanonicalCodes(t *testing.T) {
+	tests := map[string]struct {
+		input    map[byte]uint8
+		expected map[byte]prefixCode
+	}{
+		"empty": {
+			input:    map[byte]uint8{},
+			expected: map[byte]prefixCode{},
+		},
+		"same_length": {
+			input: map[byte]uint8{'c': 2, 'a': 2, 'd': 2, 'b': 2},
+			expected: map[byte]prefixCode{
+				'a': {code: 0b00, length: 2},
+				'b': {code: 0b01, length: 2},
+				'c': {code: 0b10, length: 2},
+				'd': {code: 0b11, length: 2},
+			},
+		},
+		"mixed_lengths": {
+			input: map[byte]uint8{'a': 3, 'b': 3, 'c': 2, 'd': 1},
+			expected: map[byte]prefixCode{
+				'd': {code: 0b0, length: 1},
+				'c': {code: 0b10, length: 2},
+				'a': {code: 0b110, length: 3},
+				'b': {code: 0b111, length: 3},
+			},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got := canonicalCodes(lengthTable(tc.input))
+			assert.Equal(t, codeTable(tc.expected), got)
 		})
 	}
 }
@@ -371,27 +399,33 @@ func TestCompress(t *testing.T) {
 	tests := map[string]struct {
 		input    []byte
 		expected []byte
+		bits     uint64
 		hasError bool
 	}{
 		"empty": {
 			input:    nil,
-			expected: []byte{0, 0, 0, 0},
+			expected: nil,
+			bits:     0,
 		},
 		"one_character": {
 			input:    []byte("a"),
-			expected: []byte{0, 0, 0, 3, 192},
+			expected: []byte{192},
+			bits:     3,
 		},
 		"two_characters": {
 			input:    []byte("ba"),
-			expected: []byte{0, 0, 0, 6, 248},
+			expected: []byte{248},
+			bits:     6,
 		},
 		"three_characters": {
 			input:    []byte("dcb"),
-			expected: []byte{0, 0, 0, 6, 92},
+			expected: []byte{92},
+			bits:     6,
 		},
 		"multiple_characters": {
 			input:    []byte("abbcccdddd"),
-			expected: []byte{0, 0, 0, 19, 223, 212, 0},
+			expected: []byte{223, 212, 0},
+			bits:     19,
 		},
 		"inexistent_character": {
 			input:    []byte("z"),
@@ -402,14 +436,95 @@ func TestCompress(t *testing.T) {
 	for name, tc := range tests {
 		t.Run(name, func(t *testing.T) {
 			r := bytes.NewReader(tc.input)
-			got, err := root.compress(r)
+			got := &bytes.Buffer{}
+			bits, err := root.compress(r, got)
 			if tc.hasError {
 				assert.Error(t, err)
 				return
 			}
 
 			assert.NoError(t, err)
-			assert.Equal(t, tc.expected, got)
+			assert.Equal(t, tc.expected, got.Bytes())
+			assert.Equal(t, tc.bits, bits)
+		})
+	}
+}
+
+func BenchmarkCompress(b *testing.B) {
+	words := []string{"the ", "piper ", "compresses ", "huffman ", "codes ", "of ", "a ", "tree, ", "and\n"}
+	text := &bytes.Buffer{}
+	for text.Len() < 1<<20 {
+		text.WriteString(words[rand.Intn(len(words))])
+	}
+
+	random := make([]byte, 1<<20)
+	rand.Read(random)
+
+	inputs := map[string][]byte{
+		"text":   text.Bytes(),
+		"random": random,
+	}
+
+	for _, name := range []string{"text", "random"} {
+		b.Run(name, func(b *testing.B) {
+			data := inputs[name]
+			root, err := buildHuffmanTree(bytes.NewReader(data))
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			b.SetBytes(int64(len(data)))
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				if _, err := root.compress(bytes.NewReader(data), io.Discard); err != nil {
+					b.Fatal(err)
+				}
+			}
 		})
 	}
 }
+
+func BenchmarkBuildHuffmanTree(b *testing.B) {
+	data := randSeq(1 << 20)
+
+	b.SetBytes(int64(len(data)))
+	for i := 0; i < b.N; i++ {
+		if _, err := buildHuffmanTree(bytes.NewReader(data)); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// packBits packs the given bits into bytes the same way the encoder does
+func packBits(t *testing.T, bits []bool) []byte {
+	packed := &bytes.Buffer{}
+	bw := newBitWriter(packed)
+	for _, bit := range bits {
+		assert.NoError(t, bw.writeBit(bit))
+	}
+
+	assert.NoError(t, bw.flush())
+
+	return packed.Bytes()
+}
+
+// lengthTable converts code lengths indexed by their symbol in a map to a table
+func lengthTable(lengths map[byte]uint8) [256]uint8 {
+	table := [256]uint8{}
+	for b, length := range lengths {
+		table[b] = length
+	}
+
+	return table
+}
+
+// codeTable converts prefix codes indexed by their symbol in a map to a table
+func codeTable(codes map[byte]prefixCode) [256]prefixCode {
+	table := [256]prefixCode{}
+	for b, code := range codes {
+		table[b] = code
+	}
+
+	return table
+}
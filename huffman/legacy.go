@@ -0,0 +1,139 @@
+package huffman
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// formats written before the container was introduced, they start with a version byte instead of the magic number
+const (
+	// legacyVersion starts with the big endian byte count of a json tree, so its first byte is always zero.
+	legacyVersion = 0
+	// treeVersion is a version byte followed by the tree's nodes in pre-order.
+	treeVersion = 1
+	// lengthsVersion is a version byte followed by the code length of every symbol.
+	lengthsVersion = 2
+)
+
+// decodeLegacy decodes data written before the container was introduced, and returns the number of decoded bytes
+func decodeLegacy(br *bufio.Reader, w io.Writer) (uint64, error) {
+	root, err := readLegacyTree(br)
+	if err != nil {
+		return 0, err
+	}
+
+	// read bin length (m)
+	// decode next m bits from tree
+
+	bitsCount, err := readBitsCount(br)
+	if err != nil {
+		return 0, err
+	}
+
+	written, err := decodeBits(br, root, bitsCount, w)
+	if err != nil {
+		return 0, err
+	}
+
+	// the last byte holds the last bits, nothing should come after it
+	return written, expectEOF(br)
+}
+
+// readLegacyTree reads the version byte and the tree that follows it
+func readLegacyTree(r *bufio.Reader) (*node, error) {
+	version, err := r.Peek(1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read format version: %w", err)
+	}
+
+	switch version[0] {
+	case legacyVersion:
+		// read tree's byte count (n)
+		// read next n bytes
+		// unmarshal to huffman tree
+		return readJSONTree(r)
+	case treeVersion:
+		_, _ = r.Discard(1)
+
+		// the tree's last byte is padded, so bits are read from a separate reader
+		return readTree(newBitReader(r))
+	case lengthsVersion:
+		_, _ = r.Discard(1)
+
+		lengths, err := readCodeLengths(r)
+		if err != nil {
+			return nil, err
+		}
+
+		return buildCanonicalTree(lengths)
+	default:
+		return nil, fmt.Errorf("unsupported format version %d: %w", version[0], ErrInvalidCompressedData)
+	}
+}
+
+// readTree reads a tree written by the tree version
+func readTree(br *bitReader) (*node, error) {
+	nodes := 0
+	return readNode(br, &nodes)
+}
+
+func readNode(br *bitReader, nodes *int) (*node, error) {
+	// a full binary tree with at most 256 leaves has at most 511 nodes
+	*nodes++
+	if *nodes > 2*256-1 {
+		return nil, fmt.Errorf("tree has too many nodes: %w", ErrInvalidCompressedData)
+	}
+
+	isLeaf, err := br.readBit()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tree: %w", err)
+	}
+
+	if isLeaf {
+		value, err := br.readByte()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read tree: %w", err)
+		}
+
+		return &node{IsLeaf: true, Value: value}, nil
+	}
+
+	left, err := readNode(br, nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	right, err := readNode(br, nodes)
+	if err != nil {
+		return nil, err
+	}
+
+	return &node{Left: left, Right: right}, nil
+}
+
+// readJSONTree reads a tree in the legacy json format
+func readJSONTree(r io.Reader) (*node, error) {
+	count := make([]byte, 4)
+	_, err := io.ReadFull(r, count)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tree bytes count: %w", err)
+	}
+
+	treeBytesCount := binary.BigEndian.Uint32(count)
+
+	treeBytes := make([]byte, treeBytesCount)
+	_, err = io.ReadFull(r, treeBytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tree bytes: %w", err)
+	}
+
+	root := &node{}
+	if err := json.Unmarshal(treeBytes, root); err != nil {
+		return nil, err
+	}
+
+	return root, nil
+}
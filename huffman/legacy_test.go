@@ -0,0 +1,197 @@
+package huffman
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeLegacy(t *testing.T) {
+	tree := []byte("{\"fr\":6,\"l\":{\"fr\":3,\"ilf\":true,\"v\":97},\"r\":{\"fr\":3,\"ilf\":true,\"v\":98}}")
+	withHeader := func(data ...byte) []byte {
+		header := append([]byte{0, 0, 0, byte(len(tree))}, tree...)
+		return append(header, data...)
+	}
+
+	tests := map[string]struct {
+		input    []byte
+		expected []byte
+		hasError bool
+	}{
+		"empty": {
+			input:    nil,
+			hasError: true,
+		},
+		"missing_bits_count": {
+			input:    withHeader(),
+			hasError: true,
+		},
+		"valid_data": {
+			input:    withHeader(0, 0, 0, 3, 64),
+			expected: []byte("aba"),
+		},
+		"more_binary_data": {
+			input:    withHeader(0, 0, 0, 1, 192, 1),
+			hasError: true,
+		},
+		"less_binary_data": {
+			input:    withHeader(0, 0, 0, 10, 192),
+			hasError: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := bytes.NewReader(tc.input)
+			got := &bytes.Buffer{}
+			err := decode(r, got, options{})
+			if tc.hasError {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, got.Bytes())
+		})
+	}
+}
+
+func TestDecodeVersioned(t *testing.T) {
+	// 'a' and 'b' leaves with a one bit code each
+	withHeader := func(data ...byte) []byte {
+		return append([]byte{lengthsVersion, 0, 2, 'a', 1, 'b', 1}, data...)
+	}
+
+	tests := map[string]struct {
+		input    []byte
+		expected []byte
+		hasError bool
+	}{
+		"unsupported_version": {
+			input:    []byte{5, 0, 2, 'a', 1, 'b', 1, 0, 0, 0, 0},
+			hasError: true,
+		},
+		"tree_version": {
+			input:    []byte{treeVersion, 0x58, 0x6c, 0x40, 0, 0, 0, 3, 64},
+			expected: []byte("aba"),
+		},
+		"invalid_code_lengths": {
+			input:    []byte{lengthsVersion, 0, 3, 'a', 1, 'b', 1, 'c', 1, 0, 0, 0, 0},
+			hasError: true,
+		},
+		"missing_bits_count": {
+			input:    withHeader(),
+			hasError: true,
+		},
+		"valid_data": {
+			input:    withHeader(0, 0, 0, 3, 64),
+			expected: []byte("aba"),
+		},
+		"more_binary_data": {
+			input:    withHeader(0, 0, 0, 1, 192, 1),
+			hasError: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := bytes.NewReader(tc.input)
+			got := &bytes.Buffer{}
+			err := decode(r, got, options{})
+			if tc.hasError {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, got.Bytes())
+		})
+	}
+}
+
+func TestReadTree(t *testing.T) {
+	tests := map[string]struct {
+		input    []byte
+		expected *node
+		hasError bool
+	}{
+		"empty": {
+			input:    nil,
+			hasError: true,
+		},
+		"valid_data": {
+			input:    []byte{0x58, 0x6c, 0x40},
+			expected: &node{Left: &node{IsLeaf: true, Value: 'a'}, Right: &node{IsLeaf: true, Value: 'b'}},
+		},
+		"truncated_tree": {
+			input:    []byte{0x58, 0x6c},
+			hasError: true,
+		},
+		"too_many_nodes": {
+			input:    make([]byte, 128),
+			hasError: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			br := newBitReader(bytes.NewReader(tc.input))
+			got, err := readTree(br)
+			if tc.hasError {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestReadJSONTree(t *testing.T) {
+	validData := []byte("{\"fr\":6,\"l\":{\"fr\":3,\"ilf\":true,\"v\":97},\"r\":{\"fr\":3,\"ilf\":true,\"v\":98}}")
+	tests := map[string]struct {
+		input    []byte
+		expected *node
+		hasError bool
+		count    int
+	}{
+		"empty": {
+			input:    nil,
+			hasError: true,
+		},
+		"valid_data": {
+			input:    append([]byte{0, 0, 0, byte(len(validData))}, validData...),
+			hasError: false,
+			expected: &node{Frequency: 6, Left: &node{Frequency: 3, IsLeaf: true, Value: 97}, Right: &node{Frequency: 3, IsLeaf: true, Value: 98}},
+			count:    4 + len(validData),
+		},
+		"bytes_number_less_than_expected": {
+			input:    []byte{0, 0, 0, 1, 'a', 'b', 'c'},
+			hasError: true,
+		},
+		"bytes_number_more_than_expected": {
+			input:    []byte{0, 0, 0, 10, 'a', 'b'},
+			hasError: true,
+		},
+		"invalid_json_tree": {
+			input:    append([]byte{0, 0, 0, 7}, []byte("{hello}")...),
+			hasError: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			r := bytes.NewReader(tc.input)
+			got, err := readJSONTree(r)
+			if tc.hasError {
+				assert.Error(t, err)
+				return
+			}
+
+			assert.Equal(t, tc.expected, got)
+			assert.NoError(t, err)
+		})
+	}
+}
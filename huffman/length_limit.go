@@ -0,0 +1,139 @@
+package huffman
+
+import (
+	"fmt"
+	"sort"
+)
+
+const (
+	// defaultCodeLengthLimit limits codes to 15 bits, like DEFLATE
+	defaultCodeLengthLimit = 15
+	// minCodeLengthLimit is the shortest limit leaving room for a code for each of the 256 byte values
+	minCodeLengthLimit = 8
+	// maxCodeLengthLimit keeps codes within 32 bits, so they're decoded with few overflow tables
+	maxCodeLengthLimit = 32
+)
+
+// codeLengthLimit returns the length the codes of trees are limited to, it fails if the configured limit is out of range
+func (o options) codeLengthLimit() (uint8, error) {
+	if o.maxCodeLength == 0 {
+		return defaultCodeLengthLimit, nil
+	}
+
+	if o.maxCodeLength < minCodeLengthLimit || o.maxCodeLength > maxCodeLengthLimit {
+		return 0, fmt.Errorf("invalid max code length %d, it must be between %d and %d", o.maxCodeLength, minCodeLengthLimit, maxCodeLengthLimit)
+	}
+
+	return uint8(o.maxCodeLength), nil
+}
+
+// limitCodeLengths returns a tree whose codes are at most limit bits long, and whose leaves keep their frequencies.
+// the tree itself is returned when its codes already fit, otherwise the new tree is the tree of the canonical codes
+// of the limited lengths, which are no longer optimal but close to it.
+func (n *node) limitCodeLengths(limit uint8) (*node, error) {
+	if n.height() <= int(limit) {
+		return n, nil
+	}
+
+	frequencies := [256]uint64{}
+	n.leafFrequencies(&frequencies)
+
+	root, err := buildCanonicalTree(limitLengths(n.codeLengths(), frequencies, limit))
+	if err != nil {
+		return nil, err
+	}
+
+	root.setFrequencies(&frequencies)
+
+	return root, nil
+}
+
+// limitLengths shortens the code lengths longer than limit, then lengthens shorter codes until they satisfy
+// the kraft inequality again, so a prefix code with these lengths exists.
+// codes of less frequent symbols are lengthened first, and codes of more frequent symbols are shortened
+// with whatever room is left.
+func limitLengths(lengths [256]uint8, frequencies [256]uint64, limit uint8) [256]uint8 {
+	// the kraft sum is counted in units of 2^-limit, so codes fit when it's at most 2^limit
+	kraft, capacity := uint64(0), uint64(1)<<limit
+	for b, length := range lengths {
+		if length == 0 {
+			continue
+		}
+
+		lengths[b] = min(length, limit)
+		kraft += 1 << (limit - lengths[b])
+	}
+
+	for kraft > capacity {
+		// the longest codes shorter than limit cost the least to lengthen,
+		// there's always one since all 256 codes fit when they're limit bits long
+		longest := -1
+		for b, length := range lengths {
+			if length == 0 || length == limit {
+				continue
+			}
+
+			if longest == -1 || length > lengths[longest] || (length == lengths[longest] && frequencies[b] < frequencies[longest]) {
+				longest = b
+			}
+		}
+
+		lengths[longest]++
+		kraft -= 1 << (limit - lengths[longest])
+	}
+
+	symbols := make([]int, 0, len(lengths))
+	for b, length := range lengths {
+		if length != 0 {
+			symbols = append(symbols, b)
+		}
+	}
+
+	sort.Slice(symbols, func(i, j int) bool {
+		if frequencies[symbols[i]] != frequencies[symbols[j]] {
+			return frequencies[symbols[i]] > frequencies[symbols[j]]
+		}
+
+		return symbols[i] < symbols[j]
+	})
+
+	for _, b := range symbols {
+		for lengths[b] > 1 && kraft+1<<(limit-lengths[b]) <= capacity {
+			kraft += 1 << (limit - lengths[b])
+			lengths[b]--
+		}
+	}
+
+	return lengths
+}
+
+// leafFrequencies sets the frequency of every leaf's byte
+func (n *node) leafFrequencies(frequencies *[256]uint64) {
+	if n == nil {
+		return
+	}
+
+	if n.IsLeaf {
+		frequencies[n.Value] = n.Frequency
+		return
+	}
+
+	n.Left.leafFrequencies(frequencies)
+	n.Right.leafFrequencies(frequencies)
+}
+
+// setFrequencies sets the frequency of every leaf from its byte, and of every other node to the sum of its children's
+func (n *node) setFrequencies(frequencies *[256]uint64) uint64 {
+	if n == nil {
+		return 0
+	}
+
+	if n.IsLeaf {
+		n.Frequency = frequencies[n.Value]
+		return n.Frequency
+	}
+
+	n.Frequency = n.Left.setFrequencies(frequencies) + n.Right.setFrequencies(frequencies)
+
+	return n.Frequency
+}
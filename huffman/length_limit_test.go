@@ -0,0 +1,140 @@
+package huffman
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fibonacciInput returns count symbols whose frequencies are the first fibonacci numbers,
+// the least frequent symbols of their huffman tree get codes about count bits long.
+func fibonacciInput(count int) []byte {
+	input := []byte{}
+	a, b := 1, 1
+	for i := 0; i < count; i++ {
+		input = append(input, bytes.Repeat([]byte{byte(i)}, a)...)
+		a, b = b, a+b
+	}
+
+	return input
+}
+
+// assertValidLengths checks that the lengths are within limit, satisfy the kraft inequality,
+// and that more frequent symbols don't have longer codes.
+func assertValidLengths(t *testing.T, lengths [256]uint8, frequencies [256]uint64, limit uint8) {
+	kraft := uint64(0)
+	for b, length := range lengths {
+		if length == 0 {
+			continue
+		}
+
+		assert.LessOrEqual(t, length, limit)
+		kraft += 1 << (limit - length)
+
+		for c := range lengths {
+			if lengths[c] != 0 && frequencies[c] > frequencies[b] {
+				assert.LessOrEqual(t, lengths[c], length)
+			}
+		}
+	}
+
+	assert.LessOrEqual(t, kraft, uint64(1)<<limit)
+}
+
+func TestLimitLengths(t *testing.T) {
+	tests := map[string]struct {
+		lengths     map[byte]uint8
+		frequencies map[byte]uint64
+		limit       uint8
+		expected    map[byte]uint8
+	}{
+		"fitting_lengths": {
+			lengths:     map[byte]uint8{'a': 1, 'b': 2, 'c': 2},
+			frequencies: map[byte]uint64{'a': 2, 'b': 1, 'c': 1},
+			limit:       8,
+			expected:    map[byte]uint8{'a': 1, 'b': 2, 'c': 2},
+		},
+		"chain": {
+			lengths:     map[byte]uint8{'a': 1, 'b': 2, 'c': 3, 'd': 4, 'e': 5, 'f': 5},
+			frequencies: map[byte]uint64{'a': 16, 'b': 8, 'c': 4, 'd': 2, 'e': 1, 'f': 1},
+			limit:       3,
+			// lengthening 'b' isn't enough, so 'a' is lengthened too, and the room left is used to shorten 'b' back
+			expected: map[byte]uint8{'a': 2, 'b': 2, 'c': 3, 'd': 3, 'e': 3, 'f': 3},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			frequencies := [256]uint64{}
+			for b, freq := range tc.frequencies {
+				frequencies[b] = freq
+			}
+
+			got := limitLengths(lengthTable(tc.lengths), frequencies, tc.limit)
+			assert.Equal(t, lengthTable(tc.expected), got)
+			assertValidLengths(t, got, frequencies, tc.limit)
+		})
+	}
+}
+
+func TestLimitCodeLengths(t *testing.T) {
+	input := fibonacciInput(30)
+	tree := mustBuildTree(t, input)
+	assert.Greater(t, tree.height(), 15)
+
+	frequencies := [256]uint64{}
+	tree.leafFrequencies(&frequencies)
+
+	for _, limit := range []uint8{8, 15, 32} {
+		t.Run(fmt.Sprint(limit), func(t *testing.T) {
+			limited, err := tree.limitCodeLengths(limit)
+			assert.NoError(t, err)
+			assert.LessOrEqual(t, limited.height(), int(limit))
+			assertValidLengths(t, limited.codeLengths(), frequencies, limit)
+
+			got := [256]uint64{}
+			limited.leafFrequencies(&got)
+			assert.Equal(t, frequencies, got)
+			assert.Equal(t, uint64(len(input)), limited.Frequency)
+
+			// limited codes take more bits than optimal ones
+			assert.GreaterOrEqual(t, limited.bitsCount(), tree.bitsCount())
+		})
+	}
+}
+
+func TestEncodeDecodeCodeLengthLimit(t *testing.T) {
+	input := fibonacciInput(30)
+
+	for _, limit := range []int{0, 8, 15, 32} {
+		t.Run(fmt.Sprint(limit), func(t *testing.T) {
+			compressed := &bytes.Buffer{}
+			_, err := encode(bytes.NewReader(input), compressed, options{maxCodeLength: limit})
+			assert.NoError(t, err)
+
+			// the code lengths follow the container header
+			lengths, err := readCodeLengths(bytes.NewReader(compressed.Bytes()[headerSize:]))
+			assert.NoError(t, err)
+
+			want := limit
+			if limit == 0 {
+				want = defaultCodeLengthLimit
+			}
+			for _, length := range lengths {
+				assert.LessOrEqual(t, int(length), want)
+			}
+
+			got := &bytes.Buffer{}
+			err = decode(compressed, got, options{})
+			assert.NoError(t, err)
+			assert.Equal(t, input, got.Bytes())
+		})
+	}
+
+	for _, limit := range []int{minCodeLengthLimit - 1, maxCodeLengthLimit + 1} {
+		_, err := encode(bytes.NewReader(input), &bytes.Buffer{}, options{maxCodeLength: limit})
+		assert.Error(t, err)
+	}
+}
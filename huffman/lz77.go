@@ -0,0 +1,233 @@
+package huffman
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+const (
+	// windowSize is the farthest distance of a match, distances are stored as big endian uint16s
+	windowSize = 1 << 15
+	// minMatch is the length of the shortest match, shorter repeated strings are left as literals
+	minMatch = 4
+	// maxMatch is the length of the longest match
+	maxMatch = 258
+	// hashBits is the size of the hash of the minMatch bytes starting a match
+	hashBits = 15
+	// maxChain is the number of earlier positions with the same hash compared with a position, before taking the longest match
+	maxChain = 64
+)
+
+// findSequences splits block into sequences of literals followed by a match, a string repeated within the window.
+// the literals length, the literals and the match length minus minMatch of every sequence are appended to litLens,
+// the lengths as uvarints, and the match distance minus 1 is appended to dists as a big endian uint16.
+// the last sequence has no match, so its literals length is followed by the literals ending the block.
+func findSequences(block []byte) (litLens []byte, dists []byte) {
+	// head holds the last position of every hash, and prev the previous position with the same hash of every position in the window
+	head := make([]int32, 1<<hashBits)
+	for i := range head {
+		head[i] = -1
+	}
+
+	prev := make([]int32, windowSize)
+	insert := func(i int) {
+		h := hash4(block[i:])
+		prev[i%windowSize] = head[h]
+		head[h] = int32(i)
+	}
+
+	start := 0
+	for i := 0; i+minMatch <= len(block); {
+		length, dist := longestMatch(block, i, head, prev)
+		if length < minMatch {
+			insert(i)
+			i++
+			continue
+		}
+
+		litLens = binary.AppendUvarint(litLens, uint64(i-start))
+		litLens = append(litLens, block[start:i]...)
+		litLens = binary.AppendUvarint(litLens, uint64(length-minMatch))
+		dists = binary.BigEndian.AppendUint16(dists, uint16(dist-1))
+
+		// the positions within the match are inserted too, so later strings can match them
+		end := i + length
+		for ; i < min(end, len(block)-minMatch+1); i++ {
+			insert(i)
+		}
+
+		i = end
+		start = i
+	}
+
+	litLens = binary.AppendUvarint(litLens, uint64(len(block)-start))
+	litLens = append(litLens, block[start:]...)
+
+	return litLens, dists
+}
+
+// longestMatch returns the length and the distance of the longest string starting earlier in the window that starts block[i:],
+// it follows the chain of positions with the same hash from the most recent one.
+func longestMatch(block []byte, i int, head []int32, prev []int32) (int, int) {
+	limit := min(maxMatch, len(block)-i)
+	length, dist := 0, 0
+
+	candidate := head[hash4(block[i:])]
+	for chain := 0; candidate >= 0 && i-int(candidate) <= windowSize && chain < maxChain; chain++ {
+		c := int(candidate)
+
+		n := 0
+		for n < limit && block[c+n] == block[i+n] {
+			n++
+		}
+
+		if n > length {
+			length, dist = n, i-c
+			if n == limit {
+				break
+			}
+		}
+
+		candidate = prev[c%windowSize]
+	}
+
+	return length, dist
+}
+
+// hash4 hashes the first 4 bytes of b into hashBits bits
+func hash4(b []byte) uint32 {
+	return (binary.LittleEndian.Uint32(b) * 2654435761) >> (32 - hashBits)
+}
+
+// compressSequences writes the sequences of block as two blocks written by writeBlock, each coded with its own tree,
+// the literals and lengths followed by the distances.
+func compressSequences(w io.Writer, block []byte, limit uint8) error {
+	litLens, dists := findSequences(block)
+
+	if err := writeBlock(w, litLens, Huffman, limit); err != nil {
+		return fmt.Errorf("failed to write literals and lengths: %w", err)
+	}
+
+	if err := writeBlock(w, dists, Huffman, limit); err != nil {
+		return fmt.Errorf("failed to write distances: %w", err)
+	}
+
+	return nil
+}
+
+// readSequencesTask reads the literals and lengths, and the distances of a block of size bytes written by compressSequences,
+// and returns the task decoding them.
+func readSequencesTask(br *bufio.Reader, version uint8, size uint64) (task, error) {
+	// at most size literals, and sequences whose matches have at least minMatch bytes, except the last one.
+	// every sequence has a literals length of at most size, and a match length of at most maxMatch-minMatch, which fits in 2 bytes
+	sequences := size/minMatch + 1
+	maxLitLensSize := size + sequences*uint64(len(binary.AppendUvarint(nil, size))+2)
+
+	litLensSize, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read literals and lengths size: %w", err)
+	}
+
+	if litLensSize > maxLitLensSize {
+		return nil, fmt.Errorf("literals and lengths size %d is too large: %w", litLensSize, ErrInvalidCompressedData)
+	}
+
+	litLens, err := readStreamTask(br, version, litLensSize)
+	if err != nil {
+		return nil, err
+	}
+
+	distsSize, err := binary.ReadUvarint(br)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read distances size: %w", err)
+	}
+
+	if distsSize > 2*sequences {
+		return nil, fmt.Errorf("distances size %d is too large: %w", distsSize, ErrInvalidCompressedData)
+	}
+
+	dists, err := readStreamTask(br, version, distsSize)
+	if err != nil {
+		return nil, err
+	}
+
+	return func() ([]byte, error) {
+		l, err := litLens()
+		if err != nil {
+			return nil, err
+		}
+
+		d, err := dists()
+		if err != nil {
+			return nil, err
+		}
+
+		return decodeSequences(l, d, size)
+	}, nil
+}
+
+// decodeSequences rebuilds the block of size bytes from the sequences written by findSequences
+func decodeSequences(litLens []byte, dists []byte, size uint64) ([]byte, error) {
+	block := make([]byte, 0, min(size, uint64(len(litLens))*maxMatch))
+
+	readLength := func() (uint64, error) {
+		length, n := binary.Uvarint(litLens)
+		if n <= 0 {
+			return 0, fmt.Errorf("failed to read sequence length: %w", ErrInvalidCompressedData)
+		}
+
+		litLens = litLens[n:]
+		return length, nil
+	}
+
+	for {
+		literals, err := readLength()
+		if err != nil {
+			return nil, err
+		}
+
+		if literals > uint64(len(litLens)) || literals > size-uint64(len(block)) {
+			return nil, fmt.Errorf("literals length %d is too large: %w", literals, ErrInvalidCompressedData)
+		}
+
+		block = append(block, litLens[:literals]...)
+		litLens = litLens[literals:]
+
+		if uint64(len(block)) == size {
+			break
+		}
+
+		length, err := readLength()
+		if err != nil {
+			return nil, err
+		}
+
+		length += minMatch
+		if length > maxMatch || length > size-uint64(len(block)) {
+			return nil, fmt.Errorf("match length %d is too large: %w", length, ErrInvalidCompressedData)
+		}
+
+		if len(dists) < 2 {
+			return nil, fmt.Errorf("missing match distance: %w", ErrInvalidCompressedData)
+		}
+
+		dist := int(binary.BigEndian.Uint16(dists)) + 1
+		dists = dists[2:]
+		if dist > len(block) {
+			return nil, fmt.Errorf("match distance %d is larger than the decoded data: %w", dist, ErrInvalidCompressedData)
+		}
+
+		// a match can overlap the bytes it copies, like a run of a byte that is at distance 1
+		for i := uint64(0); i < length; i++ {
+			block = append(block, block[len(block)-dist])
+		}
+	}
+
+	if len(litLens) != 0 || len(dists) != 0 {
+		return nil, fmt.Errorf("sequences are longer than the block: %w", ErrInvalidCompressedData)
+	}
+
+	return block, nil
+}
@@ -0,0 +1,259 @@
+package huffman
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// jsonLines returns n lines of JSON records, which repeat strings like logs do
+func jsonLines(n int) []byte {
+	buf := &bytes.Buffer{}
+	for i := 0; i < n; i++ {
+		fmt.Fprintf(buf, `{"id":%d,"level":"info","user":"user-%d","message":"request handled","duration_ms":%d}`+"\n", i, i%37, i*7%1000)
+	}
+
+	return buf.Bytes()
+}
+
+func TestFindSequences(t *testing.T) {
+	tests := map[string]struct {
+		input   string
+		litLens []byte
+		dists   []byte
+	}{
+		"empty": {
+			input:   "",
+			litLens: []byte{0},
+		},
+		"shorter_than_match": {
+			input:   "abc",
+			litLens: []byte{3, 'a', 'b', 'c'},
+		},
+		"repeated_string": {
+			input: "abcdabcdabcd",
+			// 4 literals, then 8 bytes at distance 4, then no literals
+			litLens: []byte{4, 'a', 'b', 'c', 'd', 8 - minMatch, 0},
+			dists:   []byte{0, 3},
+		},
+		"overlapping_match": {
+			input: "aaaaaaaa",
+			// 1 literal, then 7 bytes at distance 1
+			litLens: []byte{1, 'a', 7 - minMatch, 0},
+			dists:   []byte{0, 0},
+		},
+		"trailing_literals": {
+			input:   "abcdabcdxyz",
+			litLens: []byte{4, 'a', 'b', 'c', 'd', 4 - minMatch, 3, 'x', 'y', 'z'},
+			dists:   []byte{0, 3},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			litLens, dists := findSequences([]byte(tc.input))
+			assert.Equal(t, tc.litLens, litLens)
+			assert.Equal(t, tc.dists, dists)
+		})
+	}
+}
+
+func TestFindSequencesWindow(t *testing.T) {
+	// the repeated string is farther than the window, so it can't be matched
+	pattern := []byte("0123456789abcdef")
+	input := append(append(bytes.Clone(pattern), make([]byte, windowSize)...), pattern...)
+
+	litLens, dists := findSequences(input)
+	// the block ends with the pattern as literals, instead of a match and no literals
+	assert.True(t, bytes.HasSuffix(litLens, pattern))
+
+	got, err := decodeSequences(litLens, dists, uint64(len(input)))
+	assert.NoError(t, err)
+	assert.Equal(t, input, got)
+}
+
+func TestDecodeSequences(t *testing.T) {
+	tests := map[string]struct {
+		litLens  []byte
+		dists    []byte
+		size     uint64
+		expected []byte
+		hasError bool
+	}{
+		"valid": {
+			litLens:  []byte{4, 'a', 'b', 'c', 'd', 4, 0},
+			dists:    []byte{0, 3},
+			size:     12,
+			expected: []byte("abcdabcdabcd"),
+		},
+		"missing_literals_length": {
+			litLens:  []byte{},
+			size:     1,
+			hasError: true,
+		},
+		"missing_literals": {
+			litLens:  []byte{4, 'a', 'b'},
+			size:     4,
+			hasError: true,
+		},
+		"literals_larger_than_size": {
+			litLens:  []byte{2, 'a', 'b'},
+			size:     1,
+			hasError: true,
+		},
+		"missing_match_length": {
+			litLens:  []byte{1, 'a'},
+			size:     5,
+			hasError: true,
+		},
+		"match_larger_than_size": {
+			litLens:  []byte{1, 'a', 1, 0},
+			dists:    []byte{0, 0},
+			size:     5,
+			hasError: true,
+		},
+		"match_too_long": {
+			litLens:  []byte{1, 'a', 0xff, 0x01, 0},
+			dists:    []byte{0, 0},
+			size:     1 + 255 + 4,
+			hasError: true,
+		},
+		"missing_distance": {
+			litLens:  []byte{1, 'a', 0, 0},
+			size:     5,
+			hasError: true,
+		},
+		"distance_beyond_data": {
+			litLens:  []byte{1, 'a', 0, 0},
+			dists:    []byte{0, 1},
+			size:     5,
+			hasError: true,
+		},
+		"trailing_sequences": {
+			litLens:  []byte{1, 'a', 1, 'b'},
+			size:     1,
+			hasError: true,
+		},
+		"trailing_distances": {
+			litLens:  []byte{1, 'a'},
+			dists:    []byte{0, 0},
+			size:     1,
+			hasError: true,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := decodeSequences(tc.litLens, tc.dists, tc.size)
+			if tc.hasError {
+				assert.ErrorIs(t, err, ErrInvalidCompressedData)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, got)
+		})
+	}
+}
+
+func TestEncodeDecodeLZ77(t *testing.T) {
+	tests := map[string]struct {
+		input []byte
+		opts  options
+	}{
+		"empty": {
+			input: nil,
+		},
+		"short": {
+			input: []byte("abc"),
+		},
+		"random": {
+			input: randSeq(100000),
+		},
+		"zeros": {
+			input: make([]byte, 100000),
+		},
+		"json": {
+			input: jsonLines(10000),
+		},
+		"json_blocks": {
+			input: jsonLines(10000),
+			opts:  options{blockSize: 50000, threads: 4},
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			tc.opts.method = LZ77
+
+			compressed := &bytes.Buffer{}
+			_, err := encode(bytes.NewReader(tc.input), compressed, tc.opts)
+			assert.NoError(t, err)
+
+			got := &bytes.Buffer{}
+			err = decode(compressed, got, options{})
+			assert.NoError(t, err)
+			assert.True(t, bytes.Equal(tc.input, got.Bytes()))
+		})
+	}
+}
+
+func TestLZ77CompressesRepeatedStrings(t *testing.T) {
+	input := jsonLines(10000)
+
+	sizes := map[Method]int{}
+	for _, method := range []Method{Huffman, LZ77} {
+		compressed := &bytes.Buffer{}
+		_, err := encode(bytes.NewReader(input), compressed, options{method: method})
+		assert.NoError(t, err)
+
+		sizes[method] = compressed.Len()
+	}
+
+	// huffman codes alone can't get much below 5 bits per byte of text
+	assert.Less(t, sizes[LZ77]*3, sizes[Huffman], sizes)
+}
+
+func TestDecodeLZ77Sizes(t *testing.T) {
+	header := append(magic[:], formatVersion, flagBlocks, 0, 0, 0, 0, 0, 0, 0, 0, byte(LZ77))
+
+	tests := map[string][]byte{
+		"block_too_large": append(binary.AppendUvarint(bytes.Clone(header), 1<<36), 0, 0),
+		// a block of 1MiB whose literals and lengths are a run of 'a', and distances a run of 2^35 zeros
+		"distances_too_large": append(binary.AppendUvarint(append(binary.AppendUvarint(bytes.Clone(header), 1<<20), 1, 0, 1, 'a'), 1<<35), 0, 1, 0),
+		"literals_too_large":  append(binary.AppendUvarint(binary.AppendUvarint(bytes.Clone(header), 1<<20), 1<<35), 0, 1, 'a'),
+	}
+
+	for name, input := range tests {
+		t.Run(name, func(t *testing.T) {
+			err := decode(bytes.NewReader(input), io.Discard, options{})
+			assert.ErrorIs(t, err, ErrInvalidCompressedData)
+		})
+	}
+}
+
+func TestEncodeLZ77BlockSize(t *testing.T) {
+	_, err := encode(strings.NewReader("abc"), &bytes.Buffer{}, options{method: LZ77, blockSize: maxMethodBlockSize + 1})
+	assert.Error(t, err)
+}
+
+func TestEncodeLZ77Adaptive(t *testing.T) {
+	_, err := encode(strings.NewReader("abc"), &bytes.Buffer{}, options{method: LZ77, adaptive: true})
+	assert.Error(t, err)
+}
+
+func BenchmarkFindSequences(b *testing.B) {
+	input := jsonLines(20000)
+
+	b.SetBytes(int64(len(input)))
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		findSequences(input)
+	}
+}
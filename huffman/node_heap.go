@@ -0,0 +1,44 @@
+package huffman
+
+type nodeHeap []node
+
+func (n nodeHeap) Len() int {
+	return len(n)
+}
+
+// Less orders nodes by frequency, then leaves before internal nodes, then by value.
+// the value of an internal node is the smallest value of its leaves, so nodes in the heap are never equal,
+// and trees don't depend on the order their nodes were pushed in.
+func (n nodeHeap) Less(i, j int) bool {
+	if n[i].Frequency != n[j].Frequency {
+		return n[i].Frequency < n[j].Frequency
+	}
+
+	if n[i].IsLeaf != n[j].IsLeaf {
+		return n[i].IsLeaf
+	}
+
+	return n[i].Value < n[j].Value
+}
+
+func (n nodeHeap) Swap(i, j int) {
+	n[i], n[j] = n[j], n[i]
+}
+
+func (n *nodeHeap) Push(x any) {
+	*n = append(*n, x.(node))
+}
+
+func (n *nodeHeap) Pop() any {
+	old := *n
+	l := len(old)
+
+	x := old[l-1]
+	*n = old[0 : l-1]
+	return x
+}
+
+func (n *nodeHeap) Top() any {
+	l := len(*n)
+	return (*n)[l-1]
+}
@@ -1,7 +1,8 @@
-package main
+package huffman
 
 import (
 	"container/heap"
+	"math/rand"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -36,3 +37,30 @@ func TestHeap(t *testing.T) {
 	assert.Equal(t, node{Frequency: 20, Value: 3}, pop)
 	assert.Equal(t, h.Len(), 0)
 }
+
+func TestHeapTotalOrder(t *testing.T) {
+	// nodes of equal frequency are ordered leaves first, then by value
+	nodes := []node{
+		{Frequency: 5, Value: 'c'},
+		{Frequency: 5, IsLeaf: true, Value: 'b'},
+		{Frequency: 5, Value: 'a'},
+		{Frequency: 5, IsLeaf: true, Value: 'd'},
+		{Frequency: 1, Value: 'e'},
+	}
+
+	expected := []node{nodes[4], nodes[1], nodes[3], nodes[2], nodes[0]}
+
+	for i := 0; i < 20; i++ {
+		h := &nodeHeap{}
+		for _, j := range rand.Perm(len(nodes)) {
+			heap.Push(h, nodes[j])
+		}
+
+		got := []node{}
+		for h.Len() > 0 {
+			got = append(got, heap.Pop(h).(node))
+		}
+
+		assert.Equal(t, expected, got)
+	}
+}
@@ -0,0 +1,87 @@
+package huffman
+
+// task computes the result of a unit of work, like compressing a block
+type task func() ([]byte, error)
+
+type taskResult struct {
+	data []byte
+	err  error
+}
+
+// runOrdered runs the tasks returned by next on up to threads goroutines, until next returns a nil task.
+// results are handed to consume in the same order their tasks were returned by next.
+// next isn't called while threads tasks it returned aren't consumed yet,
+// so at most threads tasks run at once, and at most threads tasks and results are held in memory.
+// next and consume are never called concurrently with themselves.
+func runOrdered(threads int, next func() (task, error), consume func([]byte) error) error {
+	if threads < 1 {
+		threads = 1
+	}
+
+	results := make(chan chan taskResult, threads)
+	quit := make(chan struct{})
+	nextErr := make(chan error, 1)
+
+	// a slot is taken before calling next, and released once its task's result is consumed
+	slots := make(chan struct{}, threads)
+
+	go func() {
+		defer close(results)
+
+		for {
+			select {
+			case slots <- struct{}{}:
+			case <-quit:
+				return
+			}
+
+			t, err := next()
+			if err != nil {
+				nextErr <- err
+				return
+			}
+
+			if t == nil {
+				return
+			}
+
+			res := make(chan taskResult, 1)
+			select {
+			case results <- res:
+			case <-quit:
+				return
+			}
+
+			go func() {
+				data, err := t()
+				res <- taskResult{data: data, err: err}
+			}()
+		}
+	}()
+
+	for res := range results {
+		r := <-res
+		if r.err == nil {
+			r.err = consume(r.data)
+		}
+
+		if r.err != nil {
+			close(quit)
+
+			// wait for next to return, running tasks write to buffered channels so they don't block
+			for range results {
+			}
+
+			return r.err
+		}
+
+		<-slots
+	}
+
+	select {
+	case err := <-nextErr:
+		return err
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,139 @@
+package huffman
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// sleepyTasks returns a next function for count tasks, each returning its index after a random delay
+func sleepyTasks(count int, failAt int) func() (task, error) {
+	i := 0
+	return func() (task, error) {
+		if i == count {
+			return nil, nil
+		}
+
+		idx := i
+		i++
+
+		return func() ([]byte, error) {
+			time.Sleep(time.Duration(rand.Intn(1000)) * time.Microsecond)
+			if idx == failAt {
+				return nil, errors.New("task failed")
+			}
+
+			return []byte{byte(idx)}, nil
+		}, nil
+	}
+}
+
+func TestRunOrdered(t *testing.T) {
+	for _, threads := range []int{0, 1, 4, 32} {
+		got := []byte{}
+		err := runOrdered(threads, sleepyTasks(100, -1), func(data []byte) error {
+			got = append(got, data...)
+			return nil
+		})
+		assert.NoError(t, err)
+
+		want := make([]byte, 100)
+		for i := range want {
+			want[i] = byte(i)
+		}
+
+		assert.Equal(t, want, got)
+	}
+}
+
+func TestRunOrderedConcurrency(t *testing.T) {
+	for _, threads := range []int{0, 1, 2, 4} {
+		var mu sync.Mutex
+		running, peakRunning := 0, 0
+		pending, peakPending := 0, 0
+
+		sleepy := sleepyTasks(50, -1)
+		next := func() (task, error) {
+			t, err := sleepy()
+			if t == nil {
+				return t, err
+			}
+
+			mu.Lock()
+			pending++
+			peakPending = max(peakPending, pending)
+			mu.Unlock()
+
+			return func() ([]byte, error) {
+				mu.Lock()
+				running++
+				peakRunning = max(peakRunning, running)
+				mu.Unlock()
+
+				defer func() {
+					mu.Lock()
+					running--
+					mu.Unlock()
+				}()
+
+				return t()
+			}, nil
+		}
+
+		consume := func(data []byte) error {
+			mu.Lock()
+			pending--
+			mu.Unlock()
+
+			return nil
+		}
+
+		assert.NoError(t, runOrdered(threads, next, consume))
+
+		// tasks returned by next and not consumed yet hold their data in memory
+		assert.LessOrEqual(t, peakRunning, max(1, threads), "threads=%d", threads)
+		assert.LessOrEqual(t, peakPending, max(1, threads), "threads=%d", threads)
+	}
+}
+
+func TestRunOrderedErrors(t *testing.T) {
+	t.Run("task_error", func(t *testing.T) {
+		got := []byte{}
+		err := runOrdered(4, sleepyTasks(100, 50), func(data []byte) error {
+			got = append(got, data...)
+			return nil
+		})
+		assert.Error(t, err)
+		assert.Len(t, got, 50)
+	})
+
+	t.Run("consume_error", func(t *testing.T) {
+		err := runOrdered(4, sleepyTasks(100, -1), func(data []byte) error {
+			if data[0] == 10 {
+				return errors.New("consume failed")
+			}
+
+			return nil
+		})
+		assert.Error(t, err)
+	})
+
+	t.Run("next_error", func(t *testing.T) {
+		calls := 0
+		next := func() (task, error) {
+			calls++
+			if calls == 3 {
+				return nil, errors.New("next failed")
+			}
+
+			return func() ([]byte, error) { return nil, nil }, nil
+		}
+
+		err := runOrdered(4, next, func(data []byte) error { return nil })
+		assert.Error(t, err)
+	})
+}
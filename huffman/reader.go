@@ -0,0 +1,91 @@
+package huffman
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+)
+
+// Reader decompresses data written by a Writer, or by piedpiper versions that didn't write a container.
+// it implements io.ReadCloser.
+type Reader struct {
+	br   *bufio.Reader
+	opts options
+	h    header
+	// legacy is set when the data doesn't start with the magic number, as written before the container was introduced
+	legacy bool
+
+	// pr is read by Read, the goroutine decompressing the data writes to it
+	pr *io.PipeReader
+}
+
+// NewReader returns a Reader decompressing data read from r.
+// like gzip.NewReader, it reads the container header, and fails if it isn't valid.
+func NewReader(r io.Reader, opts ...Option) (*Reader, error) {
+	return newReader(r, newOptions(opts))
+}
+
+func newReader(r io.Reader, opts options) (*Reader, error) {
+	z := &Reader{
+		br:   bufio.NewReaderSize(r, buffSize),
+		opts: opts,
+	}
+
+	// data written before the container was introduced doesn't start with the magic number
+	prefix, err := z.br.Peek(len(magic))
+	if err != nil || !bytes.Equal(prefix, magic[:]) {
+		z.legacy = true
+		return z, nil
+	}
+
+	z.h, err = readHeader(z.br)
+	if err != nil {
+		return nil, err
+	}
+
+	return z, nil
+}
+
+// Read reads decompressed data, the data is decompressed as it's read.
+// the size and checksum of the data are verified before Read returns io.EOF,
+// so data read before an error may be corrupted.
+func (z *Reader) Read(p []byte) (int, error) {
+	if z.pr == nil {
+		pr, pw := io.Pipe()
+		z.pr = pr
+
+		go func() {
+			_, err := z.decode(pw)
+			pw.CloseWithError(err)
+		}()
+	}
+
+	return z.pr.Read(p)
+}
+
+// Close stops decompressing data that wasn't read, it doesn't close the underlying reader.
+func (z *Reader) Close() error {
+	if z.pr != nil {
+		// the decompressing goroutine fails to write and returns
+		z.pr.Close()
+	}
+
+	return nil
+}
+
+// WriteTo decompresses all the data and writes it to w, the size and checksum of the data are verified once it's decoded.
+// it implements io.WriterTo.
+func (z *Reader) WriteTo(w io.Writer) (int64, error) {
+	if z.pr != nil {
+		return io.Copy(w, z.pr)
+	}
+
+	// later reads return io.EOF, or the error that stopped decompressing
+	pr, pw := io.Pipe()
+	z.pr = pr
+
+	written, err := z.decode(w)
+	pw.CloseWithError(err)
+
+	return int64(written), err
+}
@@ -0,0 +1,162 @@
+package huffman
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReader(t *testing.T) {
+	input := randSeq(10000)
+
+	compressed := &bytes.Buffer{}
+	_, err := encode(bytes.NewReader(input), compressed, options{blockSize: 1000})
+	assert.NoError(t, err)
+
+	t.Run("valid", func(t *testing.T) {
+		zr, err := NewReader(bytes.NewReader(compressed.Bytes()), WithThreads(4))
+		assert.NoError(t, err)
+
+		got := &bytes.Buffer{}
+		n, err := zr.WriteTo(got)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(len(input)), n)
+		assert.Equal(t, input, got.Bytes())
+	})
+
+	t.Run("legacy", func(t *testing.T) {
+		// a pre-order tree of 'a' and 'b', followed by a bits count of 3 and the codes of "aba"
+		zr, err := NewReader(bytes.NewReader([]byte{treeVersion, 0x58, 0x6c, 0x40, 0, 0, 0, 3, 64}))
+		assert.NoError(t, err)
+
+		got := &bytes.Buffer{}
+		_, err = zr.WriteTo(got)
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("aba"), got.Bytes())
+	})
+
+	t.Run("invalid_header", func(t *testing.T) {
+		header := append([]byte{}, compressed.Bytes()[:headerSize]...)
+		header[4] = formatVersion + 1
+
+		_, err := NewReader(bytes.NewReader(header))
+		assert.ErrorIs(t, err, ErrInvalidCompressedData)
+	})
+
+	t.Run("corrupted_data", func(t *testing.T) {
+		corrupted := append([]byte{}, compressed.Bytes()...)
+		corrupted[len(corrupted)-1] ^= 1
+
+		zr, err := NewReader(bytes.NewReader(corrupted))
+		assert.NoError(t, err)
+
+		_, err = zr.WriteTo(io.Discard)
+		assert.ErrorIs(t, err, ErrInvalidCompressedData)
+	})
+}
+
+func TestReaderRead(t *testing.T) {
+	input := randSeq(10000)
+
+	compressed := &bytes.Buffer{}
+	_, err := encode(bytes.NewReader(input), compressed, options{blockSize: 1000})
+	assert.NoError(t, err)
+
+	t.Run("valid", func(t *testing.T) {
+		zr, err := NewReader(bytes.NewReader(compressed.Bytes()))
+		assert.NoError(t, err)
+		assert.NoError(t, iotest.TestReader(zr, input))
+	})
+
+	t.Run("one_byte_reads", func(t *testing.T) {
+		zr, err := NewReader(bytes.NewReader(compressed.Bytes()))
+		assert.NoError(t, err)
+
+		got, err := io.ReadAll(iotest.OneByteReader(zr))
+		assert.NoError(t, err)
+		assert.Equal(t, input, got)
+	})
+
+	t.Run("corrupted_data", func(t *testing.T) {
+		corrupted := append([]byte{}, compressed.Bytes()...)
+		corrupted[len(corrupted)-1] ^= 1
+
+		zr, err := NewReader(bytes.NewReader(corrupted))
+		assert.NoError(t, err)
+
+		_, err = io.ReadAll(zr)
+		assert.ErrorIs(t, err, ErrInvalidCompressedData)
+	})
+
+	t.Run("close", func(t *testing.T) {
+		zr, err := NewReader(bytes.NewReader(compressed.Bytes()))
+		assert.NoError(t, err)
+
+		_, err = zr.Read(make([]byte, 10))
+		assert.NoError(t, err)
+		assert.NoError(t, zr.Close())
+
+		_, err = zr.Read(make([]byte, 10))
+		assert.ErrorIs(t, err, io.ErrClosedPipe)
+	})
+
+	t.Run("read_after_write_to", func(t *testing.T) {
+		zr, err := NewReader(bytes.NewReader(compressed.Bytes()))
+		assert.NoError(t, err)
+
+		_, err = zr.WriteTo(io.Discard)
+		assert.NoError(t, err)
+
+		_, err = zr.Read(make([]byte, 10))
+		assert.ErrorIs(t, err, io.EOF)
+	})
+}
+
+func TestStackedStreams(t *testing.T) {
+	files := map[string][]byte{
+		"a.txt": []byte("aaaaaaaaaaaaaaaaaaaaabbbbbbbbbbbbbbbbb"),
+		"b.bin": randSeq(5000),
+	}
+
+	compressed := &bytes.Buffer{}
+	zw := NewWriter(compressed, WithBlockSize(1000))
+	tw := tar.NewWriter(zw)
+
+	for _, name := range []string{"a.txt", "b.bin"} {
+		err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(files[name]))})
+		assert.NoError(t, err)
+
+		_, err = tw.Write(files[name])
+		assert.NoError(t, err)
+	}
+
+	assert.NoError(t, tw.Close())
+	assert.NoError(t, zw.Close())
+
+	zr, err := NewReader(compressed)
+	assert.NoError(t, err)
+	defer zr.Close()
+
+	tr := tar.NewReader(bufio.NewReader(zr))
+	for {
+		h, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		assert.NoError(t, err)
+
+		got, err := io.ReadAll(tr)
+		assert.NoError(t, err)
+		assert.Equal(t, files[h.Name], got)
+		delete(files, h.Name)
+	}
+
+	assert.Empty(t, files)
+}
@@ -0,0 +1,83 @@
+package huffman
+
+import (
+	"errors"
+	"io"
+)
+
+var errWriterClosed = errors.New("write to a closed writer")
+
+// Writer compresses data to an underlying writer, it implements io.WriteCloser.
+// written data is compressed in blocks as it's written, Close must be called to write the last block and the checksum.
+type Writer struct {
+	w    io.Writer
+	opts options
+
+	// pw feeds written data to the goroutine compressing it, which sends its result to done
+	pw   *io.PipeWriter
+	done chan error
+
+	closed bool
+	err    error
+}
+
+// NewWriter returns a Writer compressing data to w, configured by opts
+func NewWriter(w io.Writer, opts ...Option) *Writer {
+	return &Writer{w: w, opts: newOptions(opts)}
+}
+
+// Write compresses p, it may return before p is written to the underlying writer.
+// errors of the underlying writer are returned by the following calls to Write, or by Close.
+func (z *Writer) Write(p []byte) (int, error) {
+	if z.closed {
+		return 0, errWriterClosed
+	}
+
+	if z.pw == nil {
+		z.start()
+	}
+
+	return z.pw.Write(p)
+}
+
+// start compresses the data written to the pipe until it's closed, since it can't seek it's compressed in blocks
+func (z *Writer) start() {
+	pr, pw := io.Pipe()
+	z.pw = pw
+	z.done = make(chan error, 1)
+
+	go func() {
+		_, err := encode(pr, z.w, z.opts)
+
+		// writes fail with err instead of blocking once nothing reads them
+		pr.CloseWithError(err)
+		z.done <- err
+	}()
+}
+
+// Close flushes the last block, including its last partial byte, and writes the checksum.
+// it doesn't close the underlying writer.
+func (z *Writer) Close() error {
+	if z.closed {
+		return z.err
+	}
+
+	z.closed = true
+
+	if z.pw == nil {
+		z.start()
+	}
+
+	z.pw.Close()
+	z.err = <-z.done
+
+	return z.err
+}
+
+// Compress compresses all the data read from r until EOF to w as a complete container, configured by opts,
+// and returns the number of bytes read from r.
+// unlike a Writer, when r can seek, like a file, it's read twice to compress it with a single tree, unless a block size is set.
+func Compress(w io.Writer, r io.Reader, opts ...Option) (int64, error) {
+	read, err := encode(r, w, newOptions(opts))
+	return int64(read), err
+}
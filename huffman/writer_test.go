@@ -0,0 +1,155 @@
+package huffman
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompressInput(t *testing.T) {
+	input := randSeq(10000)
+
+	tests := map[string]struct {
+		opts  []Option
+		flags uint8
+	}{
+		"default": {
+			flags: flagChecksum,
+		},
+		"blocks": {
+			opts:  []Option{WithBlockSize(1000), WithThreads(4)},
+			flags: flagChecksum | flagBlocks,
+		},
+		"adaptive": {
+			opts:  []Option{WithAdaptive()},
+			flags: flagChecksum | flagAdaptive,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			compressed := &bytes.Buffer{}
+
+			n, err := Compress(compressed, bytes.NewReader(input), tc.opts...)
+			assert.NoError(t, err)
+			assert.Equal(t, int64(len(input)), n)
+
+			h, err := readHeader(bytes.NewReader(compressed.Bytes()))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.flags, h.flags)
+
+			got := &bytes.Buffer{}
+			err = decode(compressed, got, options{})
+			assert.NoError(t, err)
+			assert.Equal(t, input, got.Bytes())
+		})
+	}
+}
+
+func TestWriterWrite(t *testing.T) {
+	input := randSeq(10000)
+
+	tests := map[string]struct {
+		opts  []Option
+		flags uint8
+	}{
+		"default": {
+			flags: flagChecksum | flagBlocks | flagUnknownSize,
+		},
+		"blocks": {
+			opts:  []Option{WithBlockSize(1000), WithThreads(4)},
+			flags: flagChecksum | flagBlocks | flagUnknownSize,
+		},
+		"adaptive": {
+			opts:  []Option{WithAdaptive()},
+			flags: flagChecksum | flagAdaptive | flagUnknownSize,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			compressed := &bytes.Buffer{}
+			zw := NewWriter(compressed, tc.opts...)
+
+			for i := 0; i < len(input); i += 7 {
+				n, err := zw.Write(input[i:min(i+7, len(input))])
+				assert.NoError(t, err)
+				assert.Equal(t, min(7, len(input)-i), n)
+			}
+
+			assert.NoError(t, zw.Close())
+
+			h, err := readHeader(bytes.NewReader(compressed.Bytes()))
+			assert.NoError(t, err)
+			assert.Equal(t, tc.flags, h.flags)
+
+			got := &bytes.Buffer{}
+			err = decode(compressed, got, options{})
+			assert.NoError(t, err)
+			assert.Equal(t, input, got.Bytes())
+		})
+	}
+}
+
+func TestWriterClose(t *testing.T) {
+	t.Run("without_writes", func(t *testing.T) {
+		compressed := &bytes.Buffer{}
+		assert.NoError(t, NewWriter(compressed).Close())
+
+		got := &bytes.Buffer{}
+		err := decode(compressed, got, options{})
+		assert.NoError(t, err)
+		assert.Empty(t, got.Bytes())
+	})
+
+	t.Run("write_after_close", func(t *testing.T) {
+		zw := NewWriter(io.Discard)
+		assert.NoError(t, zw.Close())
+		assert.NoError(t, zw.Close())
+
+		_, err := zw.Write([]byte("data"))
+		assert.ErrorIs(t, err, errWriterClosed)
+	})
+
+	t.Run("several_copies", func(t *testing.T) {
+		compressed := &bytes.Buffer{}
+		zw := NewWriter(compressed)
+
+		// bytes.Reader implements io.WriterTo, the other reader only io.Reader
+		_, err := io.Copy(zw, bytes.NewReader([]byte("first ")))
+		assert.NoError(t, err)
+		_, err = io.Copy(zw, struct{ io.Reader }{bytes.NewReader([]byte("second"))})
+		assert.NoError(t, err)
+		assert.NoError(t, zw.Close())
+
+		got := &bytes.Buffer{}
+		err = decode(compressed, got, options{})
+		assert.NoError(t, err)
+		assert.Equal(t, "first second", got.String())
+	})
+
+	t.Run("failing_writer", func(t *testing.T) {
+		errWrite := errors.New("write failed")
+		zw := NewWriter(&failingWriter{err: errWrite}, WithBlockSize(10))
+
+		// the error is returned once the first block is compressed
+		var err error
+		for i := 0; i < 100 && err == nil; i++ {
+			_, err = zw.Write([]byte("some data"))
+		}
+
+		assert.ErrorIs(t, err, errWrite)
+		assert.ErrorIs(t, zw.Close(), errWrite)
+	})
+}
+
+type failingWriter struct {
+	err error
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	return 0, w.err
+}
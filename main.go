@@ -1,13 +1,42 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"os"
+	"runtime"
+	"strings"
 
+	"github.com/mariobassem/pied_piper/huffman"
 	"github.com/urfave/cli/v2"
 )
 
+// suffix is appended to the names of compressed files
+const suffix = ".pp"
+
+// methods are the compression methods selected by --method
+var methods = map[string]huffman.Method{
+	"huffman": huffman.Huffman,
+	"lz77":    huffman.LZ77,
+	"bwt":     huffman.BWT,
+}
+
+var (
+	errUnknownSuffix = fmt.Errorf("doesn't end with %s", suffix)
+	errHasSuffix     = fmt.Errorf("already ends with %s", suffix)
+	errNotRegular    = errors.New("not a regular file")
+)
+
+// config is how each file argument is processed
+type config struct {
+	decode bool
+	stdout bool
+	keep   bool
+	force  bool
+	opts   []huffman.Option
+}
+
 func main() {
 	if err := run(); err != nil {
 		log.Printf("error: %s", err.Error())
@@ -19,57 +48,106 @@ func run() error {
 	app := &cli.App{
 		Name:                 "piedpiper",
 		Usage:                "a huffman coding compression tool",
-		UsageText:            "piedpiper OPTIONS...",
+		UsageText:            "piedpiper [OPTIONS...] [FILES...]\n   tar c dir | piedpiper | ssh host 'piedpiper --decode | tar x'",
 		EnableBashCompletion: true,
+		// short flags can be combined like gzip's, e.g. -dc
+		UseShortOptionHandling: true,
 
-		ArgsUsage: "",
+		ArgsUsage: "[FILES...]",
 		Flags: []cli.Flag{
-			&cli.StringFlag{
-				Name:     "input",
-				Usage:    "path of input file",
-				Required: true,
+			&cli.BoolFlag{
+				Name:    "decode",
+				Aliases: []string{"d"},
+				Usage:   "decode FILE" + suffix + " to FILE",
+			},
+			&cli.BoolFlag{
+				Name:    "stdout",
+				Aliases: []string{"c"},
+				Usage:   "write to stdout and keep input files",
+			},
+			&cli.BoolFlag{
+				Name:    "keep",
+				Aliases: []string{"k"},
+				Usage:   "keep input files instead of removing them",
+			},
+			&cli.BoolFlag{
+				Name:    "force",
+				Aliases: []string{"f"},
+				Usage:   "overwrite output files if they exist",
+			},
+			&cli.IntFlag{
+				Name:  "block-size",
+				Usage: "compress input in blocks of this many bytes, each with its own huffman tree (0 uses a single tree with --method huffman and 1 MiB blocks with the other methods, which take at most 16 MiB)",
 			},
 			&cli.StringFlag{
-				Name:     "output",
-				Usage:    "path of output file",
-				Required: true,
+				Name:  "method",
+				Usage: "compression method, huffman, lz77 or bwt",
+				Value: "huffman",
 			},
 			&cli.BoolFlag{
-				Name:  "decode",
-				Usage: "decode input to output",
+				Name:  "adaptive",
+				Usage: "compress in a single pass with adaptive huffman codes, without storing a tree (incompressible input grows slightly instead of being stored)",
+			},
+			&cli.IntFlag{
+				Name:  "max-code-length",
+				Usage: "limit huffman codes to this many bits, between 8 and 32",
+				Value: 15,
+			},
+			&cli.IntFlag{
+				Name:  "threads",
+				Usage: "number of blocks to compress or decompress concurrently",
+				Value: runtime.NumCPU(),
 			},
 		},
 		Action: func(ctx *cli.Context) error {
-			input := ctx.String("input")
-			output := ctx.String("output")
+			threads := ctx.Int("threads")
+			if threads < 1 {
+				return fmt.Errorf("invalid threads count %d", threads)
+			}
 
-			inputFile, err := os.Open(input)
-			if err != nil {
-				return fmt.Errorf("failed to open input file: %w", err)
+			blockSize := ctx.Int("block-size")
+			if blockSize < 0 {
+				return fmt.Errorf("invalid block size %d", blockSize)
 			}
 
-			outputFile, err := os.OpenFile(output, os.O_RDWR|os.O_CREATE, 0644)
-			if err != nil {
-				return fmt.Errorf("failed to open output file: %w", err)
+			method, ok := methods[ctx.String("method")]
+			if !ok {
+				return fmt.Errorf("unknown compression method %q", ctx.String("method"))
 			}
 
-			dec := ctx.Bool("decode")
-			if dec {
-				if err := decode(inputFile, outputFile); err != nil {
-					return fmt.Errorf("failed to decompress data: %w", err)
-				}
+			cfg := config{
+				decode: ctx.Bool("decode"),
+				stdout: ctx.Bool("stdout"),
+				keep:   ctx.Bool("keep"),
+				force:  ctx.Bool("force"),
+				opts: []huffman.Option{
+					huffman.WithBlockSize(blockSize),
+					huffman.WithThreads(threads),
+					huffman.WithMaxCodeLength(ctx.Int("max-code-length")),
+					huffman.WithMethod(method),
+				},
+			}
 
-				return nil
+			if ctx.Bool("adaptive") {
+				cfg.opts = append(cfg.opts, huffman.WithAdaptive())
 			}
 
-			compressedBytes, err := encode(inputFile)
-			if err != nil {
-				return err
+			paths := ctx.Args().Slice()
+			if len(paths) == 0 {
+				paths = []string{"-"}
 			}
 
-			_, err = outputFile.Write(compressedBytes)
-			if err != nil {
-				return fmt.Errorf("failed to write compressed data to output file: %w", err)
+			// like gzip, a failing file doesn't stop the remaining files from being processed
+			failed := 0
+			for _, path := range paths {
+				if err := processFile(path, cfg); err != nil {
+					log.Printf("error: %s: %s", path, err.Error())
+					failed++
+				}
+			}
+
+			if failed > 0 {
+				return fmt.Errorf("failed to process %d of %d files", failed, len(paths))
 			}
 
 			return nil
@@ -83,3 +161,115 @@ func run() error {
 
 	return nil
 }
+
+// processFile compresses or decompresses the file at path, stdin is processed to stdout when path is -.
+// the output file gets the mode and modification time of the input file, which is removed unless it's kept.
+func processFile(path string, cfg config) error {
+	if path == "-" {
+		output, _ := openOutput("-", cfg.force)
+		return process(os.Stdin, output, cfg)
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	if !info.Mode().IsRegular() {
+		return errNotRegular
+	}
+
+	output := "-"
+	if !cfg.stdout {
+		output, err = outputPath(path, cfg.decode)
+		if err != nil {
+			return err
+		}
+	}
+
+	inputFile, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open input file: %w", err)
+	}
+	defer inputFile.Close()
+
+	outputFile, err := openOutput(output, cfg.force)
+	if err != nil {
+		return fmt.Errorf("failed to open output file: %w", err)
+	}
+	defer outputFile.abort()
+
+	if err := process(inputFile, outputFile, cfg); err != nil {
+		return err
+	}
+
+	if cfg.stdout {
+		return nil
+	}
+
+	if err := outputFile.preserve(info); err != nil {
+		return fmt.Errorf("failed to preserve file attributes: %w", err)
+	}
+
+	if err := outputFile.commit(); err != nil {
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+
+	if cfg.keep {
+		return nil
+	}
+
+	inputFile.Close()
+
+	return os.Remove(path)
+}
+
+// process compresses or decompresses input to output
+func process(input *os.File, output *atomicFile, cfg config) error {
+	if cfg.decode {
+		zr, err := huffman.NewReader(input, cfg.opts...)
+		if err != nil {
+			return fmt.Errorf("failed to decompress data: %w", err)
+		}
+
+		if _, err := zr.WriteTo(output); err != nil {
+			return fmt.Errorf("failed to decompress data: %w", err)
+		}
+
+		return nil
+	}
+
+	if _, err := huffman.Compress(output, input, cfg.opts...); err != nil {
+		return fmt.Errorf("failed to compress data: %w", err)
+	}
+
+	return nil
+}
+
+// outputPath returns the path of the compressed or decompressed file of path
+func outputPath(path string, decode bool) (string, error) {
+	if !decode {
+		if strings.HasSuffix(path, suffix) {
+			return "", errHasSuffix
+		}
+
+		return path + suffix, nil
+	}
+
+	output := strings.TrimSuffix(path, suffix)
+	if output == path || strings.HasSuffix(output, "/") || output == "" {
+		return "", errUnknownSuffix
+	}
+
+	return output, nil
+}
+
+// openOutput creates the file at path, which only replaces an existing file once it's committed, or returns stdout when path is -
+func openOutput(path string, force bool) (*atomicFile, error) {
+	if path == "-" {
+		// stdout is never closed nor removed
+		return &atomicFile{File: os.Stdout, committed: true}, nil
+	}
+
+	return createAtomic(path, force)
+}
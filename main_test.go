@@ -0,0 +1,210 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mariobassem/pied_piper/huffman"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutputPath(t *testing.T) {
+	tests := map[string]struct {
+		path   string
+		decode bool
+		want   string
+		err    error
+	}{
+		"encode": {
+			path: "dir/file.txt",
+			want: "dir/file.txt.pp",
+		},
+		"encode_compressed": {
+			path: "dir/file.pp",
+			err:  errHasSuffix,
+		},
+		"decode": {
+			path:   "dir/file.txt.pp",
+			decode: true,
+			want:   "dir/file.txt",
+		},
+		"decode_unknown_suffix": {
+			path:   "dir/file.txt",
+			decode: true,
+			err:    errUnknownSuffix,
+		},
+		"decode_only_suffix": {
+			path:   "dir/.pp",
+			decode: true,
+			err:    errUnknownSuffix,
+		},
+	}
+
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			got, err := outputPath(tc.path, tc.decode)
+			assert.ErrorIs(t, err, tc.err)
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+// withStdio runs fn with pipes as stdin and stdout, input is written to stdin, and it returns what fn wrote to stdout
+func withStdio(t *testing.T, input []byte, fn func() error) []byte {
+	stdinR, stdinW, err := os.Pipe()
+	assert.NoError(t, err)
+
+	stdoutR, stdoutW, err := os.Pipe()
+	assert.NoError(t, err)
+
+	stdin, stdout := os.Stdin, os.Stdout
+	os.Stdin, os.Stdout = stdinR, stdoutW
+	defer func() {
+		os.Stdin, os.Stdout = stdin, stdout
+	}()
+
+	go func() {
+		stdinW.Write(input)
+		stdinW.Close()
+	}()
+
+	output := make(chan []byte)
+	go func() {
+		data, _ := io.ReadAll(stdoutR)
+		output <- data
+	}()
+
+	assert.NoError(t, fn())
+
+	stdoutW.Close()
+	stdinR.Close()
+
+	return <-output
+}
+
+func TestProcessStdio(t *testing.T) {
+	data := []byte("aaaaaaaaaaaaaaaaaaaaabbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbccccccc")
+
+	compressed := withStdio(t, data, func() error {
+		return processFile("-", config{})
+	})
+	assert.True(t, bytes.HasPrefix(compressed, []byte("PIED")))
+
+	got := withStdio(t, compressed, func() error {
+		return processFile("-", config{decode: true})
+	})
+	assert.Equal(t, data, got)
+}
+
+func TestProcessFilesToStdout(t *testing.T) {
+	dir := t.TempDir()
+	paths := []string{filepath.Join(dir, "a"), filepath.Join(dir, "b")}
+	assert.NoError(t, os.WriteFile(paths[0], []byte("first file\n"), 0644))
+	assert.NoError(t, os.WriteFile(paths[1], []byte("second file\n"), 0644))
+
+	// like gzip -c a b, the compressed files are written one after another, and decoded as one
+	compressed := withStdio(t, nil, func() error {
+		for _, path := range paths {
+			if err := processFile(path, config{stdout: true}); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	got := withStdio(t, compressed, func() error {
+		return processFile("-", config{decode: true})
+	})
+	assert.Equal(t, "first file\nsecond file\n", string(got))
+}
+
+func TestProcessFile(t *testing.T) {
+	data := []byte("aaaaaaaaaaaaaaaaaaaaabbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbccccccc")
+	mtime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	writeInput := func(t *testing.T) string {
+		path := filepath.Join(t.TempDir(), "file")
+		assert.NoError(t, os.WriteFile(path, data, 0600))
+		assert.NoError(t, os.Chtimes(path, mtime, mtime))
+
+		return path
+	}
+
+	t.Run("encode_decode", func(t *testing.T) {
+		path := writeInput(t)
+
+		assert.NoError(t, processFile(path, config{}))
+		assert.NoFileExists(t, path)
+
+		info, err := os.Stat(path + suffix)
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+		assert.True(t, mtime.Equal(info.ModTime()))
+
+		assert.NoError(t, processFile(path+suffix, config{decode: true}))
+		assert.NoFileExists(t, path+suffix)
+
+		got, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, data, got)
+
+		info, err = os.Stat(path)
+		assert.NoError(t, err)
+		assert.Equal(t, os.FileMode(0600), info.Mode().Perm())
+		assert.True(t, mtime.Equal(info.ModTime()))
+	})
+
+	t.Run("keep", func(t *testing.T) {
+		path := writeInput(t)
+
+		assert.NoError(t, processFile(path, config{keep: true}))
+		assert.FileExists(t, path)
+		assert.FileExists(t, path+suffix)
+	})
+
+	t.Run("lz77", func(t *testing.T) {
+		path := writeInput(t)
+
+		assert.NoError(t, processFile(path, config{opts: []huffman.Option{huffman.WithMethod(huffman.LZ77)}}))
+		assert.NoError(t, processFile(path+suffix, config{decode: true}))
+
+		got, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, data, got)
+	})
+
+	t.Run("existing_output", func(t *testing.T) {
+		path := writeInput(t)
+		assert.NoError(t, os.WriteFile(path+suffix, []byte("existing"), 0644))
+
+		err := processFile(path, config{})
+		assert.ErrorIs(t, err, errOutputExists)
+		assert.FileExists(t, path)
+
+		assert.NoError(t, processFile(path, config{force: true}))
+		assert.NoFileExists(t, path)
+	})
+
+	t.Run("failed_decode_keeps_input", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "file"+suffix)
+		assert.NoError(t, os.WriteFile(path, []byte("PIED corrupted"), 0644))
+
+		err := processFile(path, config{decode: true})
+		assert.ErrorIs(t, err, huffman.ErrInvalidCompressedData)
+		assert.FileExists(t, path)
+
+		entries, err := os.ReadDir(filepath.Dir(path))
+		assert.NoError(t, err)
+		assert.Len(t, entries, 1)
+	})
+
+	t.Run("directory", func(t *testing.T) {
+		err := processFile(t.TempDir(), config{})
+		assert.ErrorIs(t, err, errNotRegular)
+	})
+}